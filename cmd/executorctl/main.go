@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"executor/internal/docker"
+	"executor/internal/repository/postgres"
+	"executor/internal/repository/redis"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const queue = "bot"
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: executorctl [-config path] <command> [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  upgrade    recreate running bots on a new image in batches")
+	fmt.Fprintln(os.Stderr, "  rollback   return running bots to their previous image")
+	fmt.Fprintln(os.Stderr, "  canary     promote|abort the configured canary image")
+	fmt.Fprintln(os.Stderr, "  drift      list bots whose container spec differs from the config")
+	fmt.Fprintln(os.Stderr, "  env        list|set|unset per-bot env overrides")
+	fmt.Fprintln(os.Stderr, "  reencrypt  move every bot api token onto token_encryption.key_id")
+}
+
+func main() {
+	cfg := config.NewConfigService()
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch args[0] {
+	case "upgrade", "rollback":
+		err = rollout(cfg, models.BotMessageType(args[0]), args[1:])
+	case "canary":
+		switch {
+		case len(args) > 1 && args[1] == "promote":
+			err = rollout(cfg, models.CANARY_PROMOTE, args[2:])
+		case len(args) > 1 && args[1] == "abort":
+			err = rollout(cfg, models.CANARY_ABORT, args[2:])
+		default:
+			usage()
+			os.Exit(2)
+		}
+	case "drift":
+		err = drift(cfg, args[1:])
+	case "env":
+		if len(args) < 2 {
+			usage()
+			os.Exit(2)
+		}
+		err = env(cfg, args[1], args[2:])
+	case "reencrypt":
+		err = reencrypt(cfg)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// rollout publishes an upgrade, rollback or canary message; every executor
+// applies it to the bots it owns.
+func rollout(cfg *config.ExecutorConfig, kind models.BotMessageType, args []string) error {
+	fs := flag.NewFlagSet(string(kind), flag.ExitOnError)
+	payload := models.UpgradePayload{}
+	if kind == models.UPGRADE {
+		fs.StringVar(&payload.Image, "image", "", "image to upgrade to (default the image of each bot kind)")
+		fs.StringVar(&payload.Kind, "kind", "", "bot kind -image applies to (default telegram)")
+	}
+	fs.IntVar(&payload.BatchSize, "batch-size", 0, "bots per batch, at most upgrade.batch_size (default upgrade.batch_size)")
+	fs.IntVar(&payload.Concurrency, "concurrency", 0, "bots replaced at once within a batch, at most upgrade.concurrency (default upgrade.concurrency)")
+	maxFailures := fs.Int("max-failures", -1, "failed bots tolerated before halting (default upgrade.max_failures)")
+	fs.Parse(args)
+	if *maxFailures >= 0 {
+		payload.MaxFailures = maxFailures
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	consumer := redis.NewRepositoryConsumer(cfg, nil)
+	message := models.BotMessage{
+		ID:      fmt.Sprintf("%s:%d", kind, time.Now().UnixNano()),
+		Type:    string(kind),
+		Upgrade: &payload,
+	}
+	if err := consumer.Publish(ctx, queue, message); err != nil {
+		return err
+	}
+	fmt.Printf("%s published (id: %s)\n", kind, message.ID)
+	return nil
+}
+
+// drift compares every bot in the database with the spec the loaded config
+// produces, regardless of which executor owns it.
+func drift(cfg *config.ExecutorConfig, args []string) error {
+	fs := flag.NewFlagSet("drift", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print drifted bots as JSON")
+	fs.Parse(args)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	repo := postgres.NewPostgresRepository(cfg)
+	defer repo.Close()
+	bots, err := repo.GetAllBots(ctx)
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return err
+	}
+	// only the spec builder is used, so no runtime is needed
+	specs := docker.NewDockerService(nil, repo, nil, nil, cfg)
+	drifted, err := specs.DetectDrift(ctx, bots)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(drifted)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "BOT_ID\tEXECUTOR\tSTATE\tCONTAINER\tIMAGE\tVARIANT\tSTORED\tCURRENT\tERROR")
+	for _, d := range drifted {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.BotID, d.ExecutorID, d.State, d.ContainerID, d.Image, orDash(d.Variant), short(d.StoredHash), short(d.CurrentHash), orDash(d.Error))
+	}
+	return w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func short(hash string) string {
+	if hash == "" {
+		return "-"
+	}
+	return hash[:min(12, len(hash))]
+}
+
+// env manages the overrides of one bot. Changes apply the next time its
+// container is created; until then the bot shows up in drift.
+func env(cfg *config.ExecutorConfig, action string, args []string) error {
+	fs := flag.NewFlagSet("env "+action, flag.ExitOnError)
+	botID := fs.Int64("bot", 0, "bot_id whose overrides to manage")
+	secret := fs.Bool("secret", false, "mark the value as secret (set only)")
+	fs.Parse(args)
+	if *botID == 0 {
+		return errors.New("-bot is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	repo := postgres.NewPostgresRepository(cfg)
+	defer repo.Close()
+	service := docker.NewDockerService(nil, repo, nil, nil, cfg)
+	switch action {
+	case "list":
+		overrides, err := service.BotEnv(ctx, *botID)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE")
+		for _, o := range overrides {
+			value := o.Value
+			if o.Secret {
+				value = "********"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", o.Key, value)
+		}
+		return w.Flush()
+	case "set":
+		if fs.NArg() != 1 {
+			return errors.New("usage: executorctl env set -bot id [-secret] KEY=VALUE")
+		}
+		key, value, ok := strings.Cut(fs.Arg(0), "=")
+		if !ok {
+			return errors.New("expected KEY=VALUE")
+		}
+		return service.SetBotEnv(ctx, *botID, models.EnvOverride{Key: key, Value: value, Secret: *secret})
+	case "unset":
+		if fs.NArg() != 1 {
+			return errors.New("usage: executorctl env unset -bot id KEY")
+		}
+		return service.UnsetBotEnv(ctx, *botID, fs.Arg(0))
+	}
+	return fmt.Errorf("unknown env action: %s", action)
+}
+
+// reencrypt rewrites tokens sealed with retired keys, and plaintext ones,
+// with the active key. It is safe to rerun until it reports nothing to do.
+func reencrypt(cfg *config.ExecutorConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	repo := postgres.NewPostgresRepository(cfg)
+	defer repo.Close()
+	service := docker.NewDockerService(nil, repo, nil, nil, cfg)
+	updated, err := service.ReencryptTokens(ctx)
+	fmt.Printf("%d tokens re-encrypted with key %s\n", updated, cfg.Tokens.KeyID)
+	return err
+}
@@ -2,38 +2,145 @@ package main
 
 import (
 	"context"
+	"errors"
+	"executor/internal/canary"
 	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
 	"executor/internal/docker"
+	"executor/internal/health"
+	"executor/internal/kubernetes"
+	"executor/internal/leader"
+	"executor/internal/process"
 	"executor/internal/repository/postgres"
 	"executor/internal/repository/redis"
+	"executor/internal/sharding"
+	"executor/pkg/workerpool"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 var queue_names = []string{"bot"}
 
+var ErrDrainTimeout = errors.New("timed out waiting for in-flight messages")
+
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGKILL, syscall.SIGINT)
 	defer stop()
 	cfg := config.NewConfigService()
 	repo := postgres.NewPostgresRepository(cfg)
-	docker := docker.NewDockerService(ctx, repo, cfg)
-	consumer := redis.NewRepositoryConsumer(
-		cfg.Redis.Host,
-		cfg.Redis.RedisPassword,
-		cfg.Redis.Port,
-		cfg.Redis.DB,
-	)
+	if err := repo.Migrate(); err != nil {
+		panic(err)
+	}
+	shards := sharding.NewShardingService(repo, cfg)
+	if err := shards.Register(ctx); err != nil {
+		panic(err)
+	}
+	go shards.Run(ctx)
+	canaryService := canary.NewCanaryService(repo, cfg)
+	docker := docker.NewDockerService(newRuntime(cfg), repo, shards, canaryService, cfg)
+	pool := workerpool.New(cfg.Workers.Size, cfg.Workers.QueueSize)
+	consumer := redis.NewRepositoryConsumer(cfg, pool)
+	publish := func(ctx context.Context, message models.BotMessage) error {
+		return consumer.Publish(ctx, queue_names[0], message)
+	}
+	leaderService := leader.NewLeaderService(repo, cfg)
+	leaderService.Job("purge_stale_executors", cfg.Leader.PurgeInterval, func(ctx context.Context) error {
+		return repo.PurgeStaleExecutors(ctx, cfg.Leader.ExecutorRetention)
+	})
+	leaderService.Job("reassign_orphans", cfg.Leader.ReassignInterval, func(ctx context.Context) error {
+		return docker.ReassignOrphans(ctx, publish)
+	})
+	leaderDone := make(chan struct{})
 	go func() {
-		consumer.ConsumerMessages(ctx, queue_names, docker.DockerFactory)
+		leaderService.Run(ctx)
+		close(leaderDone)
+	}()
+	// in-flight messages outlive the signal; each one is bounded by its own deadline
+	pool.Start(context.WithoutCancel(ctx))
+	healthService := health.NewHealthService(cfg)
+	healthService.AddLivenessCheck("redis_consumer", consumer.Alive)
+	healthService.AddReadinessCheck("redis", consumer.Check)
+	healthService.AddReadinessCheck("postgres", repo.Check)
+	healthService.AddReadinessCheck("docker", docker.Check)
+	go healthService.Run()
+	// the drift watcher is not a leader job: it only looks at this
+	// executor's own bots, and recreating them has to happen here
+	go docker.WatchDrift(ctx)
+	go func() {
+		consumer.ConsumerMessages(ctx, queue_names, consumer.Idempotent(docker.DockerFactory))
 	}()
 	select {
 	case <-ctx.Done():
-		if err := docker.StopAllContainers(context.Background()); err != nil {
+		fmt.Printf("shutting down (mode: %s)...\n", cfg.Shutdown.Mode)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+		defer cancel()
+		if err := healthService.Shutdown(shutdownCtx); err != nil {
 			fmt.Println(err)
 		}
-		fmt.Println("shutting down...")
+		<-leaderDone
+		switch cfg.Shutdown.Mode {
+		case config.ShutdownDetach:
+			fmt.Println("detaching, bots are left running")
+		case config.ShutdownDrain:
+			if err := drain(shutdownCtx, pool, consumer); err != nil {
+				fmt.Println(err)
+			}
+		case config.ShutdownStopAll:
+			if err := drain(shutdownCtx, pool, consumer); err != nil {
+				fmt.Println(err)
+			}
+			stopCtx, cancelStop := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+			if err := docker.StopAllContainers(stopCtx); err != nil {
+				fmt.Println(err)
+			}
+			cancelStop()
+		case config.ShutdownHandoff:
+			if err := shards.SetDraining(shutdownCtx, true); err != nil {
+				fmt.Println(err)
+			}
+			if err := drain(shutdownCtx, pool, consumer); err != nil {
+				fmt.Println(err)
+			}
+			handoffCtx, cancelHandoff := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+			if err := docker.HandOffContainers(handoffCtx, publish); err != nil {
+				fmt.Println(err)
+			}
+			cancelHandoff()
+		}
+		repo.Close()
+		fmt.Println("shutdown complete")
+	}
+}
+
+func drain(ctx context.Context, pool *workerpool.Pool, consumer *redis.RepositoryConsumer) error {
+	fmt.Println("draining in-flight messages...")
+	done := make(chan struct{})
+	go func() {
+		pool.Close()
+		consumer.WaitBroadcasts()
+		close(done)
+	}()
+	start := time.Now()
+	select {
+	case <-done:
+		fmt.Printf("drained in %s\n", time.Since(start))
+		return nil
+	case <-ctx.Done():
+		return ErrDrainTimeout
+	}
+}
+
+func newRuntime(cfg *config.ExecutorConfig) ports.ContainerRuntime {
+	switch cfg.Runtime {
+	case "kubernetes":
+		return kubernetes.NewKubernetesRuntime(cfg)
+	case "process":
+		return process.NewProcessRuntime(cfg)
+	default:
+		return docker.NewDockerRuntime(cfg)
 	}
 }
@@ -0,0 +1,9 @@
+package dto
+
+import "database/sql"
+
+type CanaryDbo struct {
+	Image     string       `db:"image"`
+	Status    string       `db:"status"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
+}
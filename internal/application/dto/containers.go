@@ -2,6 +2,7 @@ package dto
 
 import (
 	"database/sql"
+	"encoding/json"
 	"executor/internal/core/models"
 )
 
@@ -18,6 +19,15 @@ type ContainerDbo struct {
 	Icon          string       `db:"icon"`
 	State         string       `db:"state"`
 	ApiToken      string       `db:"api_token"`
+	ApiTokenKeyID string       `db:"api_token_key_id"`
+	ExecutorID    string       `db:"executor_id"`
+	DockerHost    string       `db:"docker_host"`
+	Image         string       `db:"image"`
+	PreviousImage string       `db:"previous_image"`
+	Variant       string       `db:"image_variant"`
+	SpecHash      string       `db:"spec_hash"`
+	Kind          string       `db:"kind"`
+	Telegram      []byte       `db:"telegram"`
 	CreatedAt     sql.NullTime `db:"created_at"`
 	UpdatedAt     sql.NullTime `db:"updated_at"`
 	DeletedAt     sql.NullTime `db:"deleted_at"`
@@ -37,6 +47,15 @@ func (d *ContainerDbo) ToValue() models.Container {
 		Icon:          d.Icon,
 		State:         d.State,
 		ApiToken:      d.ApiToken,
+		ApiTokenKeyID: d.ApiTokenKeyID,
+		ExecutorID:    d.ExecutorID,
+		DockerHost:    d.DockerHost,
+		Image:         d.Image,
+		PreviousImage: d.PreviousImage,
+		Variant:       d.Variant,
+		SpecHash:      d.SpecHash,
+		Kind:          d.Kind,
+		Telegram:      unmarshalTelegram(d.Telegram),
 	}
 }
 
@@ -54,5 +73,33 @@ func ToContainerDbo(m models.Container) ContainerDbo {
 		Icon:          m.Icon,
 		State:         m.State,
 		ApiToken:      m.ApiToken,
+		ApiTokenKeyID: m.ApiTokenKeyID,
+		ExecutorID:    m.ExecutorID,
+		DockerHost:    m.DockerHost,
+		Image:         m.Image,
+		PreviousImage: m.PreviousImage,
+		Variant:       m.Variant,
+		SpecHash:      m.SpecHash,
+		Kind:          m.Kind,
+		Telegram:      marshalTelegram(m.Telegram),
 	}
 }
+
+func unmarshalTelegram(data []byte) *models.TelegramSettings {
+	if len(data) == 0 {
+		return nil
+	}
+	var settings models.TelegramSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil
+	}
+	return &settings
+}
+
+func marshalTelegram(settings *models.TelegramSettings) []byte {
+	if settings == nil {
+		return nil
+	}
+	data, _ := json.Marshal(settings)
+	return data
+}
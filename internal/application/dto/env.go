@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"database/sql"
+	"executor/internal/core/models"
+)
+
+type BotEnvDbo struct {
+	BotID     int64        `db:"bot_id"`
+	Key       string       `db:"key"`
+	Value     string       `db:"value"`
+	Secret    bool         `db:"secret"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
+}
+
+func (d BotEnvDbo) ToValue() models.EnvOverride {
+	return models.EnvOverride{Key: d.Key, Value: d.Value, Secret: d.Secret}
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "database/sql"
+
+type ExecutorDbo struct {
+	ID          string       `db:"id"`
+	Host        string       `db:"host"`
+	Draining    bool         `db:"draining"`
+	Load        int64        `db:"load"`
+	StartedAt   sql.NullTime `db:"started_at"`
+	HeartbeatAt sql.NullTime `db:"heartbeat_at"`
+}
@@ -0,0 +1,108 @@
+package canary
+
+import (
+	"context"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+var ErrNoCanary = errors.New("no canary image is configured")
+
+const (
+	StatusPromoted = "promoted"
+	StatusAborted  = "aborted"
+)
+
+type CanaryService struct {
+	repo       ports.CanaryRepository
+	kinds      map[string]config.BotKind
+	kind       string
+	image      string
+	percentage int
+	projectIDs []int64
+	userIDs    []int64
+}
+
+func NewCanaryService(repo ports.CanaryRepository, cfg *config.ExecutorConfig) *CanaryService {
+	return &CanaryService{
+		repo:       repo,
+		kinds:      cfg.BotKinds,
+		kind:       cfg.Canary.Kind,
+		image:      cfg.Canary.Image,
+		percentage: cfg.Canary.Percentage,
+		projectIDs: cfg.Canary.ProjectIDs,
+		userIDs:    cfg.Canary.UserIDs,
+	}
+}
+
+func (c *CanaryService) Image() string {
+	return c.image
+}
+
+// Kind is the bot kind the canary image applies to.
+func (c *CanaryService) Kind() string {
+	return c.kind
+}
+
+// Matches reports whether bot falls into the canary. The percentage is
+// bucketed by bot ID so a bot keeps its variant across recreations.
+func (c *CanaryService) Matches(bot models.Container) bool {
+	if slices.Contains(c.projectIDs, bot.ProjectID) || slices.Contains(c.userIDs, bot.UserID) {
+		return true
+	}
+	return c.percentage > 0 && bucket(bot.BotID) < c.percentage
+}
+
+func bucket(bot_id int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(strconv.FormatInt(bot_id, 10)))
+	return int(h.Sum32() % 100)
+}
+
+func (c *CanaryService) SelectImage(ctx context.Context, bot models.Container) (string, string, error) {
+	kind := bot.Kind
+	if kind == "" {
+		kind = config.DefaultBotKind
+	}
+	stable := c.kinds[kind].Image
+	if c.image == "" || kind != c.kind {
+		return stable, models.VariantStable, nil
+	}
+	rollout, err := c.repo.GetCanaryRollout(ctx, c.image)
+	if err != nil && !errors.Is(err, ports.ErrCanaryNotFound) {
+		return "", "", err
+	}
+	if rollout != nil {
+		switch rollout.Status {
+		case StatusPromoted:
+			return c.image, models.VariantStable, nil
+		case StatusAborted:
+			return stable, models.VariantStable, nil
+		}
+	}
+	if c.Matches(bot) {
+		return c.image, models.VariantCanary, nil
+	}
+	return stable, models.VariantStable, nil
+}
+
+// Promote makes the canary image the default for new bots.
+func (c *CanaryService) Promote(ctx context.Context) error {
+	if c.image == "" {
+		return ErrNoCanary
+	}
+	return c.repo.SetCanaryRollout(ctx, c.image, StatusPromoted)
+}
+
+// Abort sends new bots back to the stable image.
+func (c *CanaryService) Abort(ctx context.Context) error {
+	if c.image == "" {
+		return ErrNoCanary
+	}
+	return c.repo.SetCanaryRollout(ctx, c.image, StatusAborted)
+}
@@ -0,0 +1,78 @@
+package canary
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"testing"
+)
+
+type memoryRepo map[string]string
+
+func (m memoryRepo) GetCanaryRollout(ctx context.Context, image string) (*dto.CanaryDbo, error) {
+	status, ok := m[image]
+	if !ok {
+		return nil, ports.ErrCanaryNotFound
+	}
+	return &dto.CanaryDbo{Image: image, Status: status}, nil
+}
+
+func (m memoryRepo) SetCanaryRollout(ctx context.Context, image, status string) error {
+	m[image] = status
+	return nil
+}
+
+func newTestService(repo memoryRepo, canary config.Canary) *CanaryService {
+	canary.Kind = config.DefaultBotKind
+	return NewCanaryService(repo, &config.ExecutorConfig{
+		BotKinds: map[string]config.BotKind{
+			config.DefaultBotKind: {Image: "bot:1"},
+			"vk":                  {Image: "vk:1"},
+		},
+		Canary: canary,
+	})
+}
+
+func TestSelectImage(t *testing.T) {
+	repo := memoryRepo{}
+	c := newTestService(repo, config.Canary{Image: "bot:2", ProjectIDs: []int64{7}})
+	ctx := context.Background()
+
+	image, variant, _ := c.SelectImage(ctx, models.Container{BotID: 1, ProjectID: 7})
+	if image != "bot:2" || variant != models.VariantCanary {
+		t.Errorf("SelectImage() = %v, %v, want bot:2, canary", image, variant)
+	}
+	image, variant, _ = c.SelectImage(ctx, models.Container{BotID: 1, ProjectID: 8})
+	if image != "bot:1" || variant != models.VariantStable {
+		t.Errorf("SelectImage() = %v, %v, want bot:1, stable", image, variant)
+	}
+	image, variant, _ = c.SelectImage(ctx, models.Container{BotID: 1, ProjectID: 7, Kind: "vk"})
+	if image != "vk:1" || variant != models.VariantStable {
+		t.Errorf("SelectImage() for another kind = %v, %v, want vk:1, stable", image, variant)
+	}
+
+	c.Abort(ctx)
+	if image, _, _ := c.SelectImage(ctx, models.Container{BotID: 1, ProjectID: 7}); image != "bot:1" {
+		t.Errorf("SelectImage() after abort = %v, want bot:1", image)
+	}
+	c.Promote(ctx)
+	image, variant, _ = c.SelectImage(ctx, models.Container{BotID: 1, ProjectID: 8})
+	if image != "bot:2" || variant != models.VariantStable {
+		t.Errorf("SelectImage() after promote = %v, %v, want bot:2, stable", image, variant)
+	}
+}
+
+func TestMatchesPercentage(t *testing.T) {
+	c := newTestService(memoryRepo{}, config.Canary{Image: "bot:2", Percentage: 20})
+	matched := 0
+	for id := int64(1); id <= 1000; id++ {
+		if c.Matches(models.Container{BotID: id}) {
+			matched++
+		}
+	}
+	if matched < 150 || matched > 250 {
+		t.Errorf("Matches() matched %d of 1000 bots, want about 200", matched)
+	}
+}
@@ -2,30 +2,47 @@ package config
 
 import (
 	"errors"
+	"executor/internal/core/models"
 	"flag"
+	"fmt"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
 
 type Redis struct {
-	Host          string `toml:"host" env:"REDIS_HOST" env-default:"localhost"`
-	Port          int    `toml:"port" env:"REDIS_PORT" env-default:"6379"`
-	DB            int    `toml:"db" env:"REDIS_DB" env-default:"0"`
-	User          string `toml:"user" env:"REDIS_USER"`
-	Password      string `toml:"password" env:"REDIS_USER_PASSWORD"`
-	RedisPassword string `toml:"redis_password" env:"REDIS_PASSWORD"`
+	Host          string        `toml:"host" env:"REDIS_HOST" env-default:"localhost"`
+	Port          int           `toml:"port" env:"REDIS_PORT" env-default:"6379"`
+	DB            int           `toml:"db" env:"REDIS_DB" env-default:"0"`
+	User          string        `toml:"user" env:"REDIS_USER"`
+	Password      string        `toml:"password" env:"REDIS_USER_PASSWORD"`
+	RedisPassword string        `toml:"redis_password" env:"REDIS_PASSWORD"`
+	PingAttempts  int           `toml:"ping_attempts" env:"REDIS_PING_ATTEMPTS" env-default:"5"`
+	ReconnectMin  time.Duration `toml:"reconnect_min" env:"REDIS_RECONNECT_MIN" env-default:"500ms"`
+	ReconnectMax  time.Duration `toml:"reconnect_max" env:"REDIS_RECONNECT_MAX" env-default:"30s"`
+	DedupTTL      time.Duration `toml:"dedup_ttl" env:"REDIS_DEDUP_TTL" env-default:"24h"`
 }
 
 type Postgres struct {
-	Host           string `toml:"host" env:"POSTGRES_HOST" env-default:"localhost"`
-	Port           int    `toml:"port" env:"POSTGRES_PORT" env-default:"5432"`
-	User           string `toml:"user" env:"POSTGRES_USER"`
-	Password       string `toml:"password" env:"POSTGRES_PASSWORD"`
-	DBName         string `toml:"db_name" env:"POSTGRES_DB_NAME"`
-	SSLMode        string `toml:"ssl_mode" env:"POSTGRES_SSL_MODE" env-default:"disable"`
-	MigrationsPath string `toml:"migrations_path" env:"POSTGRES_MIGRATIONS_PATH" env-required:"true"`
+	Host            string        `toml:"host" env:"POSTGRES_HOST" env-default:"localhost"`
+	Port            int           `toml:"port" env:"POSTGRES_PORT" env-default:"5432"`
+	User            string        `toml:"user" env:"POSTGRES_USER"`
+	Password        string        `toml:"password" env:"POSTGRES_PASSWORD"`
+	DBName          string        `toml:"db_name" env:"POSTGRES_DB_NAME"`
+	SSLMode         string        `toml:"ssl_mode" env:"POSTGRES_SSL_MODE" env-default:"disable"`
+	MigrationsPath  string        `toml:"migrations_path" env:"POSTGRES_MIGRATIONS_PATH" env-required:"true"`
+	MaxOpenConns    int           `toml:"max_open_conns" env:"POSTGRES_MAX_OPEN_CONNS" env-default:"24"`
+	MaxIdleConns    int           `toml:"max_idle_conns" env:"POSTGRES_MAX_IDLE_CONNS" env-default:"5"`
+	ConnMaxLifetime time.Duration `toml:"conn_max_lifetime" env:"POSTGRES_CONN_MAX_LIFETIME" env-default:"30m"`
+	ConnMaxIdleTime time.Duration `toml:"conn_max_idle_time" env:"POSTGRES_CONN_MAX_IDLE_TIME" env-default:"5m"`
+	PingAttempts    int           `toml:"ping_attempts" env:"POSTGRES_PING_ATTEMPTS" env-default:"10"`
+	RetryAttempts   int           `toml:"retry_attempts" env:"POSTGRES_RETRY_ATTEMPTS" env-default:"3"`
+	RetryMin        time.Duration `toml:"retry_min" env:"POSTGRES_RETRY_MIN" env-default:"200ms"`
+	RetryMax        time.Duration `toml:"retry_max" env:"POSTGRES_RETRY_MAX" env-default:"10s"`
 }
 
 type MiniO struct {
@@ -41,13 +58,220 @@ type MiniO struct {
 }
 
 type Button struct {
-	Text string `mapstructure:"text"`
-	Data string `mapstructure:"data"`
+	Text string `mapstructure:"text" toml:"text"`
+	Data string `mapstructure:"data" toml:"data"`
+}
+
+type Telegram struct {
+	InformationURL string   `toml:"information_url" env:"TELEGRAM_INFORMATION_URL"`
+	HelloMessage   []string `toml:"hello_message"`
+	ErrorMessage   string   `toml:"error_message" env:"TELEGRAM_ERROR_MESSAGE"`
+	MainImage      string   `toml:"main_image" env:"TELEGRAM_MAIN_IMAGE"`
+	MainButtons    []Button `toml:"main_buttons"`
+	// env passes the settings as JSON in EnvName; file writes them to
+	// FilePath and points EnvName + "_FILE" at it
+	Delivery string `toml:"delivery" env:"TELEGRAM_DELIVERY" env-default:"env"`
+	EnvName  string `toml:"env_name" env:"TELEGRAM_ENV_NAME" env-default:"TELEGRAM_SETTINGS"`
+	FilePath string `toml:"file_path" env:"TELEGRAM_FILE_PATH" env-default:"/etc/bot/telegram.json"`
+}
+
+func (t Telegram) Settings() models.TelegramSettings {
+	buttons := make([]models.TelegramButton, 0, len(t.MainButtons))
+	for _, b := range t.MainButtons {
+		buttons = append(buttons, models.TelegramButton{Text: b.Text, Data: b.Data})
+	}
+	return models.TelegramSettings{
+		InformationURL: t.InformationURL,
+		HelloMessage:   t.HelloMessage,
+		ErrorMessage:   t.ErrorMessage,
+		MainImage:      t.MainImage,
+		MainButtons:    buttons,
+	}
+}
+
+type DockerHost struct {
+	Name      string `toml:"name"`
+	Address   string `toml:"address"`
+	TLSCACert string `toml:"tls_ca_cert"`
+	TLSCert   string `toml:"tls_cert"`
+	TLSKey    string `toml:"tls_key"`
+}
+
+type DockerRegistry struct {
+	Server       string `toml:"server"`
+	Username     string `toml:"username"`
+	Password     string `toml:"password"`
+	PasswordFile string `toml:"password_file"`
+	ConfigFile   string `toml:"config_file"`
 }
 
 type Docker struct {
-	ImageName string `toml:"image_name" env:"TELEGRAM_IMAGE_NAME"`
-	Timeout   int    `toml:"timeout" env:"TELEGRAM_TIMEOUT" env-default:"10"`
+	Hosts          []DockerHost     `toml:"hosts"`
+	Registries     []DockerRegistry `toml:"registries"`
+	Placement      string           `toml:"placement" env:"DOCKER_PLACEMENT" env-default:"least-loaded"`
+	ImageName      string           `toml:"image_name" env:"TELEGRAM_IMAGE_NAME"`
+	PullPolicy     string           `toml:"pull_policy" env:"DOCKER_PULL_POLICY" env-default:"if-not-present"`
+	Timeout        int              `toml:"timeout" env:"TELEGRAM_TIMEOUT" env-default:"10"`
+	MessageTimeout time.Duration    `toml:"message_timeout" env:"DOCKER_MESSAGE_TIMEOUT" env-default:"10m"`
+	CreateTimeout  time.Duration    `toml:"create_timeout" env:"DOCKER_CREATE_TIMEOUT" env-default:"30s"`
+	StartTimeout   time.Duration    `toml:"start_timeout" env:"DOCKER_START_TIMEOUT" env-default:"30s"`
+	StopTimeout    time.Duration    `toml:"stop_timeout" env:"DOCKER_STOP_TIMEOUT" env-default:"30s"`
+	PullTimeout    time.Duration    `toml:"pull_timeout" env:"DOCKER_PULL_TIMEOUT" env-default:"5m"`
+	LogsTimeout    time.Duration    `toml:"logs_timeout" env:"DOCKER_LOGS_TIMEOUT" env-default:"10s"`
+}
+
+type Upgrade struct {
+	BatchSize      int           `toml:"batch_size" env:"UPGRADE_BATCH_SIZE" env-default:"5"`
+	Concurrency    int           `toml:"concurrency" env:"UPGRADE_CONCURRENCY" env-default:"2"`
+	MaxFailures    int           `toml:"max_failures" env:"UPGRADE_MAX_FAILURES" env-default:"0"`
+	HealthTimeout  time.Duration `toml:"health_timeout" env:"UPGRADE_HEALTH_TIMEOUT" env-default:"2m"`
+	HealthInterval time.Duration `toml:"health_interval" env:"UPGRADE_HEALTH_INTERVAL" env-default:"2s"`
+	HealthGrace    time.Duration `toml:"health_grace" env:"UPGRADE_HEALTH_GRACE" env-default:"10s"`
+	Timeout        time.Duration `toml:"timeout" env:"UPGRADE_TIMEOUT" env-default:"1h"`
+}
+
+type Canary struct {
+	Kind       string  `toml:"kind" env:"CANARY_KIND" env-default:"telegram"`
+	Image      string  `toml:"image" env:"CANARY_IMAGE"`
+	Percentage int     `toml:"percentage" env:"CANARY_PERCENTAGE" env-default:"0"`
+	ProjectIDs []int64 `toml:"project_ids" env:"CANARY_PROJECT_IDS" env-separator:","`
+	UserIDs    []int64 `toml:"user_ids" env:"CANARY_USER_IDS" env-separator:","`
+}
+
+type Drift struct {
+	Policy   string        `toml:"policy" env:"DRIFT_POLICY" env-default:"report"`
+	Interval time.Duration `toml:"interval" env:"DRIFT_INTERVAL" env-default:"5m"`
+}
+
+const DefaultBotKind = "telegram"
+
+// BotPayloadFields are the payload fields a bot kind may require.
+var BotPayloadFields = []string{"project_id", "user_id", "name", "description", "icon", "api_token"}
+
+type BotKind struct {
+	Image          string   `toml:"image"`
+	NamePrefix     string   `toml:"name_prefix"`
+	Env            []string `toml:"env"`
+	RequiredFields []string `toml:"required_fields"`
+	CPUs           float64  `toml:"cpus"`
+	MemoryMB       int64    `toml:"memory_mb"`
+}
+
+type Container struct {
+	Env []string `toml:"env"`
+	// ProtectedEnv lists the variables per-bot overrides may not replace; a
+	// trailing * matches a prefix
+	ProtectedEnv []string `toml:"protected_env"`
+}
+
+// DefaultProtectedEnv keeps overrides away from a bot's identity, its token
+// and the shared infrastructure credentials.
+var DefaultProtectedEnv = []string{"CONTAINER_*", "TELEGRAM_BOT_TOKEN", "POSTGRES_*", "MINIO_*"}
+
+// Secrets controls how secret variables reach a bot. With file delivery each
+// one is written to Dir/NAME and the bot gets NAME_FILE instead of NAME, so
+// the value does not show up in the container's inspect output.
+type Secrets struct {
+	Delivery string `toml:"delivery" env:"SECRETS_DELIVERY" env-default:"env"`
+	Dir      string `toml:"dir" env:"SECRETS_DIR" env-default:"/run/secrets"`
+	// Env lists the secret variables; overrides flagged secret are added
+	Env []string `toml:"env"`
+}
+
+// TokenEncryption encrypts bot api tokens at rest. New tokens use KeyID;
+// the other keys stay listed until executorctl reencrypt has moved every
+// row off them. An empty KeyID stores new tokens in plaintext.
+type TokenEncryption struct {
+	KeyID string          `toml:"key_id" env:"TOKEN_ENCRYPTION_KEY_ID"`
+	Keys  []EncryptionKey `toml:"keys"`
+}
+
+// EncryptionKey is a base64 encoded 32 byte key, inline or read from KeyFile.
+type EncryptionKey struct {
+	ID      string `toml:"id"`
+	Key     string `toml:"key"`
+	KeyFile string `toml:"key_file"`
+}
+
+var DefaultSecretEnv = []string{
+	"POSTGRES_PASSWORD",
+	"MINIO_ROOT_PASSWORD",
+	"OPEN_ROUTER_API_TOKEN",
+	"GIGACHAT_AUTHORIZATION_KEY",
+	"TELEGRAM_BOT_TOKEN",
+}
+
+var envPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*\*?$`)
+
+// Protected reports whether key may not be overridden per bot. The telegram
+// settings variables are always protected.
+func (c Container) Protected(key string, telegram Telegram) bool {
+	if key == telegram.EnvName || key == telegram.EnvName+"_FILE" {
+		return true
+	}
+	for _, p := range c.ProtectedEnv {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if p == key {
+			return true
+		}
+	}
+	return false
+}
+
+type Workers struct {
+	Size      int `toml:"size" env:"WORKERS_SIZE" env-default:"8"`
+	QueueSize int `toml:"queue_size" env:"WORKERS_QUEUE_SIZE" env-default:"16"`
+}
+
+type Executor struct {
+	ID                string        `toml:"id" env:"EXECUTOR_ID"`
+	Placement         string        `toml:"placement" env:"EXECUTOR_PLACEMENT" env-default:"hash"`
+	HeartbeatInterval time.Duration `toml:"heartbeat_interval" env:"EXECUTOR_HEARTBEAT_INTERVAL" env-default:"10s"`
+	HeartbeatTTL      time.Duration `toml:"heartbeat_ttl" env:"EXECUTOR_HEARTBEAT_TTL" env-default:"30s"`
+}
+
+type Leader struct {
+	Name              string        `toml:"name" env:"LEADER_NAME" env-default:"executor"`
+	Interval          time.Duration `toml:"interval" env:"LEADER_INTERVAL" env-default:"5s"`
+	PurgeInterval     time.Duration `toml:"purge_interval" env:"LEADER_PURGE_INTERVAL" env-default:"10m"`
+	ReassignInterval  time.Duration `toml:"reassign_interval" env:"LEADER_REASSIGN_INTERVAL" env-default:"1m"`
+	ExecutorRetention time.Duration `toml:"executor_retention" env:"LEADER_EXECUTOR_RETENTION" env-default:"24h"`
+}
+
+type ShutdownMode string
+
+const (
+	ShutdownDetach  ShutdownMode = "detach"
+	ShutdownDrain   ShutdownMode = "drain"
+	ShutdownStopAll ShutdownMode = "stop-all"
+	ShutdownHandoff ShutdownMode = "handoff"
+)
+
+type Shutdown struct {
+	Mode            ShutdownMode  `toml:"mode" env:"SHUTDOWN_MODE" env-default:"drain"`
+	Timeout         time.Duration `toml:"timeout" env:"SHUTDOWN_TIMEOUT" env-default:"1m"`
+	StopConcurrency int           `toml:"stop_concurrency" env:"SHUTDOWN_STOP_CONCURRENCY" env-default:"8"`
+}
+
+type Http struct {
+	Host         string        `toml:"host" env:"HTTP_HOST" env-default:"0.0.0.0"`
+	Port         int           `toml:"port" env:"HTTP_PORT" env-default:"8080"`
+	CheckTimeout time.Duration `toml:"check_timeout" env:"HTTP_CHECK_TIMEOUT" env-default:"2s"`
+}
+
+type Kubernetes struct {
+	Kubeconfig string `toml:"kubeconfig" env:"KUBECONFIG"`
+	Namespace  string `toml:"namespace" env:"KUBERNETES_NAMESPACE" env-default:"default"`
+	LogLines   int64  `toml:"log_lines" env:"KUBERNETES_LOG_LINES" env-default:"100"`
+}
+
+type Process struct {
+	Binary   string   `toml:"binary" env:"PROCESS_BINARY"`
+	Args     []string `toml:"args" env:"PROCESS_ARGS" env-separator:" "`
+	StateDir string   `toml:"state_dir" env:"PROCESS_STATE_DIR" env-default:".executor"`
 }
 
 type OpenRouterAi struct {
@@ -65,13 +289,29 @@ type GigaChatAi struct {
 }
 
 type ExecutorConfig struct {
-	Redis        Redis        `toml:"redis"`
-	Postgres     Postgres     `toml:"postgres"`
-	MiniO        MiniO        `toml:"minio"`
-	Docker       Docker       `toml:"docker"`
-	SearchUrl    string       `toml:"search_url" env:"SEARCH_URL" env-required:"true"`
-	OpenRouterAi OpenRouterAi `toml:"open_router_ai"`
-	GigaChatAi   GigaChatAi   `toml:"gigachat"`
+	Runtime      string             `toml:"runtime" env:"EXECUTOR_RUNTIME" env-default:"docker"`
+	Redis        Redis              `toml:"redis"`
+	Postgres     Postgres           `toml:"postgres"`
+	MiniO        MiniO              `toml:"minio"`
+	Docker       Docker             `toml:"docker"`
+	Kubernetes   Kubernetes         `toml:"kubernetes"`
+	Process      Process            `toml:"process"`
+	Container    Container          `toml:"container"`
+	Secrets      Secrets            `toml:"secrets"`
+	Tokens       TokenEncryption    `toml:"token_encryption"`
+	BotKinds     map[string]BotKind `toml:"bot_kinds"`
+	Telegram     Telegram           `toml:"telegram"`
+	Http         Http               `toml:"http"`
+	Workers      Workers            `toml:"workers"`
+	Upgrade      Upgrade            `toml:"upgrade"`
+	Canary       Canary             `toml:"canary"`
+	Drift        Drift              `toml:"drift"`
+	Shutdown     Shutdown           `toml:"shutdown"`
+	Executor     Executor           `toml:"executor"`
+	Leader       Leader             `toml:"leader"`
+	SearchUrl    string             `toml:"search_url" env:"SEARCH_URL" env-required:"true"`
+	OpenRouterAi OpenRouterAi       `toml:"open_router_ai"`
+	GigaChatAi   GigaChatAi         `toml:"gigachat"`
 	configPath   string
 }
 
@@ -103,5 +343,202 @@ func (cfg *ExecutorConfig) load() error {
 		return err
 	}
 
+	if cfg.Executor.ID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("executor.id: not set and hostname is unavailable: %w", err)
+		}
+		cfg.Executor.ID = host
+	}
+
+	cfg.defaultBotKinds()
+	if cfg.Container.ProtectedEnv == nil {
+		cfg.Container.ProtectedEnv = DefaultProtectedEnv
+	}
+	if cfg.Secrets.Env == nil {
+		cfg.Secrets.Env = DefaultSecretEnv
+	}
+
+	return cfg.validate()
+}
+
+// defaultBotKinds keeps configs written before bot kinds working: the
+// telegram kind falls back to docker.image_name and container.env.
+func (cfg *ExecutorConfig) defaultBotKinds() {
+	if cfg.BotKinds == nil {
+		cfg.BotKinds = make(map[string]BotKind)
+	}
+	kind, ok := cfg.BotKinds[DefaultBotKind]
+	if !ok {
+		kind = BotKind{NamePrefix: "tg-", RequiredFields: []string{"api_token"}}
+	}
+	if kind.Image == "" {
+		kind.Image = cfg.Docker.ImageName
+	}
+	cfg.BotKinds[DefaultBotKind] = kind
+	for name, kind := range cfg.BotKinds {
+		if len(kind.Env) == 0 {
+			kind.Env = cfg.Container.Env
+		}
+		cfg.BotKinds[name] = kind
+	}
+}
+
+func (cfg *ExecutorConfig) validate() error {
+	switch cfg.Runtime {
+	case "docker", "kubernetes":
+	case "process":
+		if cfg.Process.Binary == "" {
+			return errors.New("process.binary: required for the process runtime")
+		}
+	default:
+		return fmt.Errorf("runtime: unsupported value %q", cfg.Runtime)
+	}
+	switch cfg.Shutdown.Mode {
+	case ShutdownDetach, ShutdownDrain, ShutdownStopAll, ShutdownHandoff:
+	default:
+		return fmt.Errorf("shutdown.mode: unsupported value %q", cfg.Shutdown.Mode)
+	}
+	if cfg.Shutdown.StopConcurrency < 1 {
+		return errors.New("shutdown.stop_concurrency: must be positive")
+	}
+	switch cfg.Docker.PullPolicy {
+	case "always", "if-not-present", "never":
+	default:
+		return fmt.Errorf("docker.pull_policy: unsupported value %q", cfg.Docker.PullPolicy)
+	}
+	names := make(map[string]bool, len(cfg.Docker.Hosts))
+	for i, host := range cfg.Docker.Hosts {
+		if host.Name == "" || host.Address == "" {
+			return fmt.Errorf("docker.hosts[%d]: name and address are required", i)
+		}
+		if names[host.Name] {
+			return fmt.Errorf("docker.hosts[%d]: duplicate name %q", i, host.Name)
+		}
+		names[host.Name] = true
+		if (host.TLSCert == "") != (host.TLSKey == "") {
+			return fmt.Errorf("docker.hosts[%d]: tls_cert and tls_key must be set together", i)
+		}
+	}
+	servers := make(map[string]bool, len(cfg.Docker.Registries))
+	for i, reg := range cfg.Docker.Registries {
+		if reg.Server == "" {
+			return fmt.Errorf("docker.registries[%d]: server is required", i)
+		}
+		if servers[reg.Server] {
+			return fmt.Errorf("docker.registries[%d]: duplicate server %q", i, reg.Server)
+		}
+		servers[reg.Server] = true
+		sources := 0
+		for _, v := range []string{reg.Password, reg.PasswordFile, reg.ConfigFile} {
+			if v != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return fmt.Errorf("docker.registries[%d]: exactly one of password, password_file or config_file is required", i)
+		}
+		if reg.ConfigFile == "" && reg.Username == "" {
+			return fmt.Errorf("docker.registries[%d]: username is required", i)
+		}
+	}
+	if cfg.Upgrade.BatchSize < 1 {
+		return errors.New("upgrade.batch_size: must be at least 1")
+	}
+	if cfg.Upgrade.Concurrency < 1 {
+		return errors.New("upgrade.concurrency: must be at least 1")
+	}
+	if cfg.Upgrade.MaxFailures < 0 {
+		return errors.New("upgrade.max_failures: must not be negative")
+	}
+	for name, kind := range cfg.BotKinds {
+		if kind.Image == "" {
+			return fmt.Errorf("bot_kinds.%s: image is required", name)
+		}
+		if kind.CPUs < 0 || kind.MemoryMB < 0 {
+			return fmt.Errorf("bot_kinds.%s: cpus and memory_mb must not be negative", name)
+		}
+		for _, field := range kind.RequiredFields {
+			if !slices.Contains(BotPayloadFields, field) {
+				return fmt.Errorf("bot_kinds.%s: unknown required field %q", name, field)
+			}
+		}
+	}
+	for i, p := range cfg.Container.ProtectedEnv {
+		if !envPattern.MatchString(p) {
+			return fmt.Errorf("container.protected_env[%d]: invalid variable name %q", i, p)
+		}
+	}
+	switch cfg.Secrets.Delivery {
+	case "env":
+	case "file":
+		if !strings.HasPrefix(cfg.Secrets.Dir, "/") {
+			return errors.New("secrets.dir: must be absolute")
+		}
+	default:
+		return fmt.Errorf("secrets.delivery: unsupported value %q", cfg.Secrets.Delivery)
+	}
+	for i, name := range cfg.Secrets.Env {
+		if !envPattern.MatchString(name) || strings.HasSuffix(name, "*") {
+			return fmt.Errorf("secrets.env[%d]: invalid variable name %q", i, name)
+		}
+	}
+	keyIDs := make(map[string]bool, len(cfg.Tokens.Keys))
+	for i, key := range cfg.Tokens.Keys {
+		if key.ID == "" {
+			return fmt.Errorf("token_encryption.keys[%d]: id is required", i)
+		}
+		if keyIDs[key.ID] {
+			return fmt.Errorf("token_encryption.keys[%d]: duplicate id %q", i, key.ID)
+		}
+		keyIDs[key.ID] = true
+		if (key.Key == "") == (key.KeyFile == "") {
+			return fmt.Errorf("token_encryption.keys[%d]: exactly one of key or key_file is required", i)
+		}
+	}
+	if cfg.Tokens.KeyID != "" && !keyIDs[cfg.Tokens.KeyID] {
+		return fmt.Errorf("token_encryption.key_id: unknown key %q", cfg.Tokens.KeyID)
+	}
+	if err := cfg.Telegram.Settings().Validate(); err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	switch cfg.Telegram.Delivery {
+	case "env":
+	case "file":
+		if !strings.HasPrefix(cfg.Telegram.FilePath, "/") {
+			return errors.New("telegram.file_path: must be absolute")
+		}
+	default:
+		return fmt.Errorf("telegram.delivery: unsupported value %q", cfg.Telegram.Delivery)
+	}
+	if cfg.Telegram.EnvName == "" {
+		return errors.New("telegram.env_name: required")
+	}
+	switch cfg.Drift.Policy {
+	case "report", "recreate":
+	default:
+		return fmt.Errorf("drift.policy: unsupported value %q", cfg.Drift.Policy)
+	}
+	if _, ok := cfg.BotKinds[cfg.Canary.Kind]; cfg.Canary.Image != "" && !ok {
+		return fmt.Errorf("canary.kind: unknown bot kind %q", cfg.Canary.Kind)
+	}
+	if cfg.Canary.Percentage < 0 || cfg.Canary.Percentage > 100 {
+		return errors.New("canary.percentage: must be between 0 and 100")
+	}
+	if cfg.Executor.HeartbeatTTL <= cfg.Executor.HeartbeatInterval {
+		return errors.New("executor.heartbeat_ttl: must exceed executor.heartbeat_interval")
+	}
+	if max, need := cfg.Postgres.MaxOpenConns, cfg.minOpenConns(); max > 0 && max < need {
+		return fmt.Errorf("postgres.max_open_conns: must be at least %d for the configured workers, upgrade and shutdown concurrency", need)
+	}
 	return nil
 }
+
+// minOpenConns is the pool size below which bot locks can starve queries:
+// every in-flight operation pins one connection for its bot lock and needs
+// another for queries. Workers and an upgrade's replacements run at the same
+// time; shutdown stops bots once the workers are done. The leader lease pins
+// one more for as long as it is held.
+func (cfg *ExecutorConfig) minOpenConns() int {
+	return 2*max(cfg.Workers.Size+cfg.Upgrade.Concurrency, cfg.Shutdown.StopConcurrency) + 1
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+)
+
 type Container struct {
 	Id            int64
 	Port          int64
@@ -13,4 +20,77 @@ type Container struct {
 	Icon          string
 	State         string
 	ApiToken      string
+	// ApiTokenKeyID names the key ApiToken is encrypted with; empty means
+	// the token is stored in plaintext
+	ApiTokenKeyID string
+	ExecutorID    string
+	DockerHost    string
+	Image         string
+	PreviousImage string
+	Variant       string
+	SpecHash      string
+	Kind          string
+	Telegram      *TelegramSettings
+}
+
+const (
+	VariantStable = "stable"
+	VariantCanary = "canary"
+)
+
+// SpecHashLabel carries ContainerSpec.Hash on the container itself.
+const SpecHashLabel = "executor/spec-hash"
+
+// ContainerFile is written into the container at Path before it starts.
+// Runtimes that cannot place it there set Env to wherever it ended up.
+type ContainerFile struct {
+	Path    string
+	Env     string
+	Content []byte
+}
+
+type ContainerSpec struct {
+	Name        string
+	Image       string
+	Env         []string
+	Labels      map[string]string
+	Files       []ContainerFile
+	CPUs        float64
+	MemoryBytes int64
+}
+
+// Hash identifies the effective configuration of a container: its image, env,
+// labels, files and resource limits. The name is left out since every
+// container gets a fresh one.
+func (s ContainerSpec) Hash() string {
+	h := sha256.New()
+	h.Write([]byte(s.Image))
+	h.Write([]byte{0})
+	env := slices.Clone(s.Env)
+	slices.Sort(env)
+	for _, kv := range env {
+		h.Write([]byte(kv))
+		h.Write([]byte{0})
+	}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		if k != SpecHashLabel {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		h.Write([]byte(k + "=" + s.Labels[k]))
+		h.Write([]byte{0})
+	}
+	for _, f := range s.Files {
+		h.Write([]byte(f.Path + "=" + f.Env + "="))
+		h.Write(f.Content)
+		h.Write([]byte{0})
+	}
+	// limits were added later; leaving them out when unset keeps older hashes
+	if s.CPUs > 0 || s.MemoryBytes > 0 {
+		h.Write([]byte(strconv.FormatFloat(s.CPUs, 'g', -1, 64) + "/" + strconv.FormatInt(s.MemoryBytes, 10)))
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
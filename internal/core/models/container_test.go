@@ -0,0 +1,25 @@
+package models
+
+import "testing"
+
+func TestSpecHash(t *testing.T) {
+	a := ContainerSpec{
+		Name:   "tg-a",
+		Image:  "bot@sha256:1",
+		Env:    []string{"A=1", "B=2"},
+		Labels: map[string]string{"x": "1"},
+	}
+	b := ContainerSpec{
+		Name:   "tg-b",
+		Image:  "bot@sha256:1",
+		Env:    []string{"B=2", "A=1"},
+		Labels: map[string]string{"x": "1", SpecHashLabel: "old"},
+	}
+	if a.Hash() != b.Hash() {
+		t.Errorf("Hash() differs for specs with the same image, env and labels")
+	}
+	b.Env = []string{"A=1", "B=3"}
+	if a.Hash() == b.Hash() {
+		t.Errorf("Hash() is equal for specs with different env")
+	}
+}
@@ -0,0 +1,9 @@
+package models
+
+// EnvOverride replaces or adds one variable in a single bot's environment.
+// Secret values are never printed.
+type EnvOverride struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
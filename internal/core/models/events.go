@@ -1,19 +1,37 @@
 package models
 
 type BotMessage struct {
-	Type      string     `json:"type"`
-	Payload   BotPayload `json:"payload"`
-	Timestamp int64      `json:"timestamp"`
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   BotPayload      `json:"payload"`
+	Upgrade   *UpgradePayload `json:"upgrade,omitempty"`
+	Timestamp int64           `json:"timestamp"`
 }
 
 type BotMessageType string
 
 const (
-	RUN  BotMessageType = "run"
-	STOP BotMessageType = "stop"
+	RUN      BotMessageType = "run"
+	STOP     BotMessageType = "stop"
+	UPGRADE  BotMessageType = "upgrade"
+	ROLLBACK BotMessageType = "rollback"
+
+	CANARY_PROMOTE BotMessageType = "canary-promote"
+	CANARY_ABORT   BotMessageType = "canary-abort"
 )
 
+// Broadcast reports whether every executor handles the message for the bots
+// it owns, rather than the single owner of Payload.BotID.
+func (m BotMessage) Broadcast() bool {
+	switch BotMessageType(m.Type) {
+	case UPGRADE, ROLLBACK, CANARY_PROMOTE, CANARY_ABORT:
+		return true
+	}
+	return false
+}
+
 type BotPayload struct {
+	Kind        string `json:"kind,omitempty"`
 	BotID       int64  `json:"bot_id"`
 	ProjectID   int64  `json:"project_id"`
 	UserID      int64  `json:"user_id"`
@@ -21,4 +39,19 @@ type BotPayload struct {
 	Description string `json:"description"`
 	Icon        string `json:"icon"`
 	ApiToken    string `json:"api_token"`
+	// ApiTokenKeyID is set when ApiToken is already encrypted, as in handoffs
+	ApiTokenKeyID string `json:"api_token_key_id,omitempty"`
+	// Telegram overrides the executor's [telegram] settings for this bot
+	Telegram *TelegramSettings `json:"telegram,omitempty"`
+}
+
+// UpgradePayload overrides the [upgrade] config for a single rollout. An
+// empty Image upgrades every bot to the image of its kind; an explicit Image
+// only applies to bots of Kind, the telegram kind by default.
+type UpgradePayload struct {
+	Kind        string `json:"kind,omitempty"`
+	Image       string `json:"image,omitempty"`
+	BatchSize   int    `json:"batch_size,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+	MaxFailures *int   `json:"max_failures,omitempty"`
 }
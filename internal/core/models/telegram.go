@@ -0,0 +1,71 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+var ErrInvalidTelegram = errors.New("invalid telegram settings")
+
+// telegram limits callback data to 64 bytes
+const maxCallbackData = 64
+
+type TelegramButton struct {
+	Text string `json:"text"`
+	Data string `json:"data"`
+}
+
+// TelegramSettings is what a telegram bot receives as its [telegram]
+// config. In a payload every set field overrides the executor's config.
+type TelegramSettings struct {
+	InformationURL string           `json:"information_url,omitempty"`
+	HelloMessage   []string         `json:"hello_message,omitempty"`
+	ErrorMessage   string           `json:"error_message,omitempty"`
+	MainImage      string           `json:"main_image,omitempty"`
+	MainButtons    []TelegramButton `json:"main_buttons,omitempty"`
+}
+
+// Merge returns s with every field set in override replaced.
+func (s TelegramSettings) Merge(override *TelegramSettings) TelegramSettings {
+	if override == nil {
+		return s
+	}
+	if override.InformationURL != "" {
+		s.InformationURL = override.InformationURL
+	}
+	if len(override.HelloMessage) > 0 {
+		s.HelloMessage = override.HelloMessage
+	}
+	if override.ErrorMessage != "" {
+		s.ErrorMessage = override.ErrorMessage
+	}
+	if override.MainImage != "" {
+		s.MainImage = override.MainImage
+	}
+	if len(override.MainButtons) > 0 {
+		s.MainButtons = override.MainButtons
+	}
+	return s
+}
+
+func (s TelegramSettings) Validate() error {
+	for name, raw := range map[string]string{"information_url": s.InformationURL, "main_image": s.MainImage} {
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: %s: must be an http(s) url, got %q", ErrInvalidTelegram, name, raw)
+		}
+	}
+	for i, b := range s.MainButtons {
+		if b.Text == "" || b.Data == "" {
+			return fmt.Errorf("%w: main_buttons[%d]: text and data are required", ErrInvalidTelegram, i)
+		}
+		if len(b.Data) > maxCallbackData {
+			return fmt.Errorf("%w: main_buttons[%d]: data exceeds %d bytes", ErrInvalidTelegram, i, maxCallbackData)
+		}
+	}
+	return nil
+}
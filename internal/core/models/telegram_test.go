@@ -0,0 +1,31 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTelegramMerge(t *testing.T) {
+	base := TelegramSettings{HelloMessage: []string{"hi"}, ErrorMessage: "oops"}
+	got := base.Merge(&TelegramSettings{ErrorMessage: "custom"})
+	if got.ErrorMessage != "custom" || len(got.HelloMessage) != 1 {
+		t.Errorf("Merge() = %+v, want hello kept and error overridden", got)
+	}
+}
+
+func TestTelegramValidate(t *testing.T) {
+	for _, s := range []TelegramSettings{
+		{MainImage: "not a url"},
+		{MainButtons: []TelegramButton{{Text: "ask"}}},
+		{MainButtons: []TelegramButton{{Text: "ask", Data: strings.Repeat("x", 65)}}},
+	} {
+		if err := s.Validate(); !errors.Is(err, ErrInvalidTelegram) {
+			t.Errorf("Validate(%+v) error = %v, want %v", s, err, ErrInvalidTelegram)
+		}
+	}
+	ok := TelegramSettings{MainImage: "https://cdn.example.com/a.png", MainButtons: []TelegramButton{{Text: "ask", Data: "ask_question"}}}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/models"
+)
+
+type CanaryRepository interface {
+	GetCanaryRollout(ctx context.Context, image string) (*dto.CanaryDbo, error)
+	SetCanaryRollout(ctx context.Context, image, status string) error
+}
+
+// Canary picks the image for bots created without a pinned one and records
+// whether the configured canary image was promoted or aborted.
+type Canary interface {
+	Image() string
+	Kind() string
+	SelectImage(ctx context.Context, bot models.Container) (image, variant string, err error)
+	Promote(ctx context.Context) error
+	Abort(ctx context.Context) error
+}
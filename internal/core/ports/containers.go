@@ -10,11 +10,22 @@ type ContainersRepository interface {
 	GetContainerByContainerId(ctx context.Context, container_id string) (*dto.ContainerDbo, error)
 	GetContainerByBotInfo(ctx context.Context, bot dto.ContainerDbo) (*dto.ContainerDbo, error)
 	GetAllBots(ctx context.Context) ([]dto.ContainerDbo, error)
+	GetBotsByExecutor(ctx context.Context, executor_id string) ([]dto.ContainerDbo, error)
 	CreateBot(ctx context.Context, bot dto.ContainerDbo) (int64, error)
 	UpdateBotById(ctx context.Context, bot dto.ContainerDbo) (*dto.ContainerDbo, error)
 	DeleteBotById(ctx context.Context, id int64) error
+	RetireBotById(ctx context.Context, id int64) error
+	RestoreBotById(ctx context.Context, id int64) error
 	DeleteBotByContainerId(ctx context.Context, container_id string) error
 	DeleteBotByBotInfo(ctx context.Context, bot dto.ContainerDbo) error
 	SetBotState(ctx context.Context, state string, id int64) error
 	StopBotState(ctx context.Context, id, bot_id int64) error
+	SetBotExecutor(ctx context.Context, id int64, executor_id string) error
+	SetBotVariant(ctx context.Context, id int64, variant string) error
+	SetBotImage(ctx context.Context, id int64, image, previous_image, variant string) error
+	SetBotToken(ctx context.Context, id int64, api_token, key_id string) error
+	LockBot(ctx context.Context, bot_id int64) (func() error, error)
+	GetBotEnv(ctx context.Context, bot_id int64) ([]dto.BotEnvDbo, error)
+	SetBotEnv(ctx context.Context, env dto.BotEnvDbo) error
+	DeleteBotEnv(ctx context.Context, bot_id int64, key string) error
 }
@@ -0,0 +1,13 @@
+package ports
+
+import "errors"
+
+// Repositories return these so services can tell an empty result from a
+// failure without depending on a concrete adapter.
+var (
+	ErrBotNotFound       = errors.New("could not find bot_container by id")
+	ErrBotsNotFound      = errors.New("could not find any bot_containers")
+	ErrBotEnvNotFound    = errors.New("could not find bot env override")
+	ErrCanaryNotFound    = errors.New("could not find canary rollout")
+	ErrExecutorsNotFound = errors.New("could not find any live executors")
+)
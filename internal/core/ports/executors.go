@@ -0,0 +1,36 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"time"
+)
+
+// ErrNotOwner is returned by message handlers for bots another executor owns,
+// so the consumer can tell a skipped message from an applied one.
+var ErrNotOwner = errors.New("bot is owned by another executor")
+
+type ExecutorsRepository interface {
+	RegisterExecutor(ctx context.Context, id, host string) error
+	HeartbeatExecutor(ctx context.Context, id string) error
+	SetExecutorDraining(ctx context.Context, id string, draining bool) error
+	GetLiveExecutors(ctx context.Context, ttl time.Duration) ([]dto.ExecutorDbo, error)
+	IsExecutorAlive(ctx context.Context, id string, ttl time.Duration) (bool, error)
+	PurgeStaleExecutors(ctx context.Context, older_than time.Duration) error
+}
+
+type Ownership interface {
+	ExecutorID() string
+	ExecutorAlive(ctx context.Context, id string) (bool, error)
+	Owns(ctx context.Context, bot_id int64, existing *dto.ContainerDbo) (bool, error)
+}
+
+type Lease interface {
+	Alive(ctx context.Context) error
+	Release() error
+}
+
+type LeaseRepository interface {
+	TryLock(ctx context.Context, name string) (Lease, error)
+}
@@ -0,0 +1,42 @@
+package ports
+
+import (
+	"context"
+	"executor/internal/core/models"
+	"io"
+)
+
+// ContainerRuntime runs bot workloads. Hosts are the placement targets a
+// runtime exposes (Docker daemons, a Kubernetes namespace, the local
+// machine); the chosen one is recorded on every bot_containers row.
+type ContainerRuntime interface {
+	Name() string
+	Hosts() []string
+	Create(ctx context.Context, bot models.Container, spec models.ContainerSpec) (*models.Container, error)
+	Start(ctx context.Context, bot models.Container) error
+	Stop(ctx context.Context, bot models.Container) error
+	Remove(ctx context.Context, bot models.Container) error
+	Logs(ctx context.Context, bot models.Container, w io.Writer) error
+	Check(ctx context.Context) error
+	IsNotFound(err error) bool
+}
+
+// ImagePuller is implemented by runtimes that fetch images themselves before
+// Create, according to the configured pull policy.
+type ImagePuller interface {
+	EnsureImage(ctx context.Context, host, image string) error
+	PullImage(ctx context.Context, host, image string) error
+}
+
+// ImageResolver is implemented by runtimes that can pin a tag to the digest
+// it currently points at, so recreated bots keep running the same image.
+type ImageResolver interface {
+	ResolveImage(ctx context.Context, host, image string) (string, error)
+}
+
+// HealthReporter is implemented by runtimes that can tell whether a started
+// bot is up. Healthy returns false while the bot is still starting and an
+// error once it has failed.
+type HealthReporter interface {
+	Healthy(ctx context.Context, bot models.Container) (bool, error)
+}
@@ -0,0 +1,117 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"fmt"
+)
+
+// PromoteCanary makes the canary image the default for new bots and moves
+// this executor's stable bots onto the digest its canary bots run.
+func (d *DockerService) PromoteCanary(ctx context.Context, payload *models.UpgradePayload) error {
+	if err := d.canary.Promote(ctx); err != nil {
+		return err
+	}
+	bots, err := d.runningBots(ctx)
+	if err != nil {
+		return err
+	}
+	bots = d.botsOfKind(bots, d.canary.Kind())
+	target := ""
+	for _, c := range bots {
+		if c.Variant == models.VariantCanary {
+			target = c.Image
+			break
+		}
+	}
+	if target == "" {
+		if target, err = d.resolveTarget(ctx, d.canary.Image()); err != nil {
+			return err
+		}
+	}
+	tasks := make([]upgradeTask, 0, len(bots))
+	for _, c := range bots {
+		if c.Variant == models.VariantCanary || c.Image == target {
+			if err := d.repo.SetBotVariant(ctx, c.Id, models.VariantStable); err != nil {
+				return err
+			}
+			continue
+		}
+		tasks = append(tasks, upgradeTask{bot: c, image: target, previous: c.Image, variant: models.VariantStable})
+	}
+	return d.rollout(ctx, "canary-promote", tasks, d.upgradeOptions(payload))
+}
+
+// AbortCanary sends new bots back to the stable image and moves this
+// executor's canary bots onto it. Running bots are replaced; stopped ones
+// lose their container and start on the stable image on their next run.
+func (d *DockerService) AbortCanary(ctx context.Context, payload *models.UpgradePayload) error {
+	if err := d.canary.Abort(ctx); err != nil {
+		return err
+	}
+	bots, err := d.repo.GetBotsByExecutor(ctx, d.owner.ExecutorID())
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return err
+	}
+	_, kind, err := d.botKind(d.canary.Kind())
+	if err != nil {
+		return err
+	}
+	target, err := d.resolveTarget(ctx, kind.Image)
+	if err != nil {
+		return err
+	}
+	tasks := make([]upgradeTask, 0, len(bots))
+	var errs []error
+	for _, c := range bots {
+		if c.Variant != models.VariantCanary {
+			continue
+		}
+		if c.State != "running" {
+			if err := d.moveStopped(ctx, c, target); err != nil {
+				errs = append(errs, fmt.Errorf("[bot_id: %d] %w", c.BotID, err))
+			}
+			continue
+		}
+		tasks = append(tasks, upgradeTask{bot: c, image: target, previous: c.Image, variant: models.VariantStable})
+	}
+	if err := d.rollout(ctx, "canary-abort", tasks, d.upgradeOptions(payload)); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// moveStopped points a stopped bot's row at image and removes its container,
+// so the next run recreates it from the row instead of starting the old one.
+func (d *DockerService) moveStopped(ctx context.Context, c dto.ContainerDbo, image string) error {
+	unlock, err := d.repo.LockBot(ctx, c.BotID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	current, err := d.repo.GetContainerById(ctx, c.Id)
+	if err != nil {
+		return err
+	}
+	if current.State == "running" || current.Image != c.Image {
+		fmt.Printf("[bot_id: %d] changed since the rollout started, skipping\n", current.BotID)
+		return nil
+	}
+	if err := d.runtime.Remove(ctx, current.ToValue()); err != nil && !d.runtime.IsNotFound(err) {
+		return err
+	}
+	return d.repo.SetBotImage(ctx, current.Id, image, current.Image, models.VariantStable)
+}
+
+func (d *DockerService) botsOfKind(bots []dto.ContainerDbo, kind string) []dto.ContainerDbo {
+	filtered := make([]dto.ContainerDbo, 0, len(bots))
+	for _, c := range bots {
+		if name, _, err := d.botKind(c.Kind); err == nil && name == kind {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
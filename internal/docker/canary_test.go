@@ -0,0 +1,34 @@
+package docker
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"testing"
+)
+
+func TestAbortCanaryMovesStoppedBots(t *testing.T) {
+	repo := &fakeRepo{bots: []dto.ContainerDbo{
+		{Id: 1, BotID: 1, State: "running", ExecutorID: "a", Image: "bot:canary", Variant: models.VariantCanary},
+		{Id: 2, BotID: 2, State: "stopped", ExecutorID: "a", Image: "bot:canary", Variant: models.VariantCanary},
+		{Id: 3, BotID: 3, State: "stopped", ExecutorID: "a", Image: "bot:1", Variant: models.VariantStable},
+	}}
+	d := newRolloutService(t, repo, &fakeRuntime{})
+	d.canary = &fakeCanary{kind: config.DefaultBotKind}
+	if err := d.AbortCanary(context.Background(), nil); err != nil {
+		t.Fatalf("AbortCanary() error = %v", err)
+	}
+	for _, id := range []int64{1, 2} {
+		rows := repo.live(id)
+		if len(rows) != 1 || rows[0].Image != "bot:2" || rows[0].Variant != models.VariantStable {
+			t.Errorf("bot_id %d rows = %+v, want one stable row on bot:2", id, rows)
+		}
+	}
+	if rows := repo.live(2); len(rows) == 1 && (rows[0].State != "stopped" || rows[0].PreviousImage != "bot:canary") {
+		t.Errorf("bot_id 2 row = %+v, want it kept stopped with previous image bot:canary", rows[0])
+	}
+	if rows := repo.live(3); len(rows) != 1 || rows[0].Image != "bot:1" {
+		t.Errorf("bot_id 3 rows = %+v, want the stable bot untouched", rows)
+	}
+}
@@ -2,137 +2,188 @@ package docker
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"executor/internal/application/dto"
 	"executor/internal/core/config"
 	"executor/internal/core/models"
 	"executor/internal/core/ports"
-	freeport "executor/pkg/free-port"
+	"executor/internal/envtemplate"
+	"executor/internal/placement"
+	"executor/internal/tokencrypt"
 	"fmt"
-	"io"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/image"
-	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
+	"golang.org/x/sync/errgroup"
 )
 
 type DockerService struct {
-	client *client.Client
-	repo   ports.ContainersRepository
-	ctx    context.Context
-	cfg    *config.ExecutorConfig
+	runtime      ports.ContainerRuntime
+	hostStrategy placement.Strategy
+	repo         ports.ContainersRepository
+	owner        ports.Ownership
+	canary       ports.Canary
+	env          map[string]*envtemplate.EnvTemplate
+	tokens       *tokencrypt.Keyring
+	cfg          *config.ExecutorConfig
 }
 
-func NewDockerService(ctx context.Context, repo ports.ContainersRepository, cfg *config.ExecutorConfig) *DockerService {
-	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+func NewDockerService(runtime ports.ContainerRuntime, repo ports.ContainersRepository, owner ports.Ownership, canary ports.Canary, cfg *config.ExecutorConfig) *DockerService {
+	strategy, err := placement.NewStrategy(cfg.Docker.Placement)
+	if err != nil {
+		panic(err)
+	}
+	env := make(map[string]*envtemplate.EnvTemplate, len(cfg.BotKinds))
+	for name, kind := range cfg.BotKinds {
+		tmpl, err := envtemplate.New(kind.Env, cfg)
+		if err != nil {
+			panic(fmt.Errorf("bot_kinds.%s: %w", name, err))
+		}
+		env[name] = tmpl
+	}
+	tokens, err := tokencrypt.New(cfg.Tokens)
 	if err != nil {
 		panic(err)
 	}
 	return &DockerService{
-		client: cli,
-		repo:   repo,
-		ctx:    ctx,
-		cfg:    cfg,
+		runtime:      runtime,
+		hostStrategy: strategy,
+		repo:         repo,
+		owner:        owner,
+		canary:       canary,
+		env:          env,
+		tokens:       tokens,
+		cfg:          cfg,
 	}
 }
 
-func (d *DockerService) PullImage(ctx context.Context, img string) error {
-	reader, err := d.client.ImagePull(d.ctx, img, image.PullOptions{})
-	d.client.ImageImport(d.ctx, image.ImportSource{SourceName: img}, img, image.ImportOptions{})
-	if err != nil {
-		return err
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
-	io.Copy(os.Stdout, reader)
-	return nil
+	return context.WithTimeout(ctx, timeout)
 }
 
-func (d *DockerService) CreateContainerConfig(ctx context.Context, bot models.Container) (*container.Config, error) {
-	return &container.Config{
-		Image: d.cfg.Docker.ImageName,
-		Tty:   true,
-		Env: []string{
-			fmt.Sprintf("POSTGRES_HOST=%s", d.cfg.Postgres.Host),
-			fmt.Sprintf("POSTGRES_PORT=%d", d.cfg.Postgres.Port),
-			fmt.Sprintf("POSTGRES_USER=%s", d.cfg.Postgres.User),
-			fmt.Sprintf("POSTGRES_PASSWORD=%s", d.cfg.Postgres.Password),
-			fmt.Sprintf("POSTGRES_DB_NAME=%s", d.cfg.Postgres.DBName),
-			fmt.Sprintf("POSTGRES_SSL_MODE=%s", d.cfg.Postgres.SSLMode),
-			fmt.Sprintf("POSTGRES_MIGRATIONS_PATH=%s", d.cfg.Postgres.MigrationsPath),
-			fmt.Sprintf("MINIO_HOST=%s", d.cfg.MiniO.Host),
-			fmt.Sprintf("MINIO_PORT=%d", d.cfg.MiniO.Port),
-			fmt.Sprintf("MINIO_ROOT_USER=%s", d.cfg.MiniO.User),
-			fmt.Sprintf("MINIO_ROOT_PASSWORD=%s", d.cfg.MiniO.Password),
-			fmt.Sprintf("MINIO_ARTICLES_BUCKET=%s", d.cfg.MiniO.BucketArticles),
-			fmt.Sprintf("MINIO_ATTACHMENTS_BUCKET=%s", d.cfg.MiniO.BucketAttachments),
-			fmt.Sprintf("MINIO_AVATARS_BUCKET=%s", d.cfg.MiniO.BucketAvatars),
-			fmt.Sprintf("MINIO_USE_SSL=%t", d.cfg.MiniO.UseSsl),
-			fmt.Sprintf("MINIO_URL_LIFETIME=%s", d.cfg.MiniO.UrlLifetime),
-			fmt.Sprintf("TELEGRAM_BOT_TOKEN=%s", bot.ApiToken),
-			fmt.Sprintf("SEARCH_URL=%s", d.cfg.SearchUrl),
-			fmt.Sprintf("CONTAINER_BOT_ID=%d", bot.BotID),
-			fmt.Sprintf("CONTAINER_PROJECT_ID=%d", bot.ProjectID),
-			fmt.Sprintf("CONTAINER_USER_ID=%d", bot.UserID),
-			fmt.Sprintf("CONTAINER_NAME=%s", bot.Name),
-			fmt.Sprintf("CONTAINER_DESCRIPTION=%s", bot.Description),
-			fmt.Sprintf("CONTAINER_ICON=%s", bot.Icon),
-			fmt.Sprintf("OPEN_ROUTER_API_TOKEN=%s", d.cfg.OpenRouterAi.Token),
-			fmt.Sprintf("OPEN_ROUTER_API_MODEL=%s", d.cfg.OpenRouterAi.Model),
-			fmt.Sprintf("OPEN_ROUTER_API_URL=%s", d.cfg.OpenRouterAi.URL),
-			fmt.Sprintf("GIGACHAT_GRPC_ADDRESS=%s", d.cfg.GigaChatAi.GRPCAddress),
-			fmt.Sprintf("GIGACHAT_AUTH_URL=%s", d.cfg.GigaChatAi.AuthURL),
-			fmt.Sprintf("GIGACHAT_AUTHORIZATION_KEY=%s", d.cfg.GigaChatAi.AuthorizationKey),
-			fmt.Sprintf("GIGACHAT_SCOPE=%s", d.cfg.GigaChatAi.Scope),
-			fmt.Sprintf("GIGACHAT_MODEL=%s", d.cfg.GigaChatAi.Model),
-		},
+func (d *DockerService) CreateContainerConfig(ctx context.Context, bot models.Container) (*models.ContainerSpec, error) {
+	name, kind, err := d.botKind(bot.Kind)
+	if err != nil {
+		return nil, err
+	}
+	// the token is only decrypted here, into this copy of the bot
+	bot.ApiToken, err = d.tokens.Decrypt(bot.ApiToken, bot.ApiTokenKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("bot_id: %d: api_token: %w", bot.BotID, err)
+	}
+	env, err := d.env[name].Render(bot)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := d.BotEnv(ctx, bot.BotID)
+	if err != nil {
+		return nil, err
+	}
+	env = d.applyEnvOverrides(bot.BotID, env, overrides)
+	spec := &models.ContainerSpec{
+		Name:        bot.ContainerName,
+		Image:       bot.Image,
+		Env:         env,
+		CPUs:        kind.CPUs,
+		MemoryBytes: kind.MemoryMB * 1024 * 1024,
 		Labels: map[string]string{
 			"co.elastic.logs/enabled":             "true",
 			"co.elastic.logs/json.overwrite_keys": "true",
 			"co.elastic.logs/json.add_error_key":  "true",
 			"co.elastic.logs/json.expand_keys":    "true",
 		},
-	}, nil
+	}
+	d.moveSecretsToFiles(spec, overrides)
+	if name == config.DefaultBotKind {
+		if err := d.addTelegramSettings(spec, bot.Telegram); err != nil {
+			return nil, err
+		}
+	}
+	return spec, nil
 }
 
-func (d *DockerService) CreateContainer(ctx context.Context, bot models.Container) (*container.CreateResponse, int64, error) {
-	port, err := freeport.GetFreePort()
+// addTelegramSettings hands the bot its merged [telegram] settings as JSON,
+// either inline in the env or as a file named by <env_name>_FILE.
+func (d *DockerService) addTelegramSettings(spec *models.ContainerSpec, override *models.TelegramSettings) error {
+	data, err := json.Marshal(d.cfg.Telegram.Settings().Merge(override))
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
-	hostBinding := nat.PortBinding{
-		HostIP:   "0.0.0.0",
-		HostPort: fmt.Sprintf("%d", port),
+	if d.cfg.Telegram.Delivery == "file" {
+		spec.Files = append(spec.Files, models.ContainerFile{
+			Path:    d.cfg.Telegram.FilePath,
+			Env:     d.cfg.Telegram.EnvName + "_FILE",
+			Content: data,
+		})
+		return nil
 	}
-	containerPort, err := nat.NewPort("tcp", fmt.Sprintf("%d", port))
+	spec.Env = append(spec.Env, d.cfg.Telegram.EnvName+"="+string(data))
+	return nil
+}
+
+func (d *DockerService) CreateContainer(ctx context.Context, bot models.Container) (*models.Container, error) {
+	host, err := d.placeHost(ctx, bot.BotID)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	bot.DockerHost = host
+	if bot.Image == "" {
+		bot.Image, bot.Variant, err = d.canary.SelectImage(ctx, bot)
+		if err != nil {
+			return nil, err
+		}
 	}
-	portBinding := nat.PortMap{containerPort: []nat.PortBinding{hostBinding}}
-	cfg, err := d.CreateContainerConfig(ctx, bot)
+	if err := d.prepareImage(ctx, &bot); err != nil {
+		return nil, err
+	}
+	spec, err := d.CreateContainerConfig(ctx, bot)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	resp, err := d.client.ContainerCreate(d.ctx, cfg, &container.HostConfig{
-		PortBindings: portBinding,
-		RestartPolicy: container.RestartPolicy{
-			Name: "unless-stopped",
-		},
-		NetworkMode: "host",
-	}, nil, nil, bot.ContainerName)
+	bot.SpecHash = spec.Hash()
+	spec.Labels[models.SpecHashLabel] = bot.SpecHash
+	createCtx, cancel := withTimeout(ctx, d.cfg.Docker.CreateTimeout)
+	defer cancel()
+	created, err := d.runtime.Create(createCtx, bot, *spec)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	bot.ContainerID = resp.ID
-	bot.Port = int64(port)
-	dbo := dto.ToContainerDbo(bot)
-	fmt.Println(dbo)
-	id, err := d.repo.CreateBot(ctx, dbo)
+	id, err := d.repo.CreateBot(ctx, dto.ToContainerDbo(*created))
 	if err != nil {
-		return nil, 0, err
+		if rerr := d.runtime.Remove(context.WithoutCancel(ctx), *created); rerr != nil {
+			fmt.Printf("[%s] could not remove orphaned container: %s\n", created.ContainerID, rerr.Error())
+		}
+		return nil, err
 	}
-	return &resp, id, nil
+	created.Id = id
+	fmt.Printf("[%s] container created on %s/%s from %s\n", created.ContainerID, d.runtime.Name(), host, created.Image)
+	return created, nil
+}
+
+// prepareImage makes the image available on the bot's host and pins it to a
+// digest, so the bot only moves to a new image on an explicit upgrade.
+func (d *DockerService) prepareImage(ctx context.Context, bot *models.Container) error {
+	ctx, cancel := withTimeout(ctx, d.cfg.Docker.PullTimeout)
+	defer cancel()
+	if puller, ok := d.runtime.(ports.ImagePuller); ok {
+		if err := puller.EnsureImage(ctx, bot.DockerHost, bot.Image); err != nil {
+			return err
+		}
+	}
+	if resolver, ok := d.runtime.(ports.ImageResolver); ok {
+		pinned, err := resolver.ResolveImage(ctx, bot.DockerHost, bot.Image)
+		if err != nil {
+			return err
+		}
+		bot.Image = pinned
+	}
+	return nil
 }
 
 func (d *DockerService) GetContainerByBotInfo(ctx context.Context, bot models.Container) (*models.Container, error) {
@@ -144,20 +195,19 @@ func (d *DockerService) GetContainerByBotInfo(ctx context.Context, bot models.Co
 	return &res, nil
 }
 
-func (d *DockerService) RunContainer(ctx context.Context, container_id string, db_id int64) error {
-	if err := d.repo.SetBotState(ctx, "running", db_id); err != nil {
+func (d *DockerService) RunContainer(ctx context.Context, bot models.Container) error {
+	if err := d.repo.SetBotState(ctx, "running", bot.Id); err != nil {
 		return err
 	}
-	return d.client.ContainerStart(d.ctx, container_id, container.StartOptions{})
+	ctx, cancel := withTimeout(ctx, d.cfg.Docker.StartTimeout)
+	defer cancel()
+	return d.runtime.Start(ctx, bot)
 }
 
-func (d *DockerService) GetContainerLogs(ctx context.Context, id string) error {
-	out, err := d.client.ContainerLogs(d.ctx, id, container.LogsOptions{ShowStdout: true})
-	if err != nil {
-		return err
-	}
-	io.Copy(os.Stdout, out)
-	return nil
+func (d *DockerService) GetContainerLogs(ctx context.Context, bot models.Container) error {
+	ctx, cancel := withTimeout(ctx, d.cfg.Docker.LogsTimeout)
+	defer cancel()
+	return d.runtime.Logs(ctx, bot, os.Stdout)
 }
 
 func (d *DockerService) StopContainer(ctx context.Context, bot models.Container) error {
@@ -166,97 +216,349 @@ func (d *DockerService) StopContainer(ctx context.Context, bot models.Container)
 	if err != nil {
 		return err
 	}
-	if err := d.client.ContainerStop(ctx, cont.ContainerID, container.StopOptions{Timeout: &d.cfg.Docker.Timeout}); err != nil {
+	if err := d.stopContainer(ctx, cont.ToValue()); err != nil {
 		return err
 	}
 	return d.repo.StopBotState(ctx, cont.Id, cont.BotID)
 }
 
+func (d *DockerService) stopContainer(ctx context.Context, bot models.Container) error {
+	ctx, cancel := withTimeout(ctx, d.cfg.Docker.StopTimeout)
+	defer cancel()
+	return d.runtime.Stop(ctx, bot)
+}
+
+func (d *DockerService) placeHost(ctx context.Context, bot_id int64) (string, error) {
+	hosts := d.runtime.Hosts()
+	if len(hosts) == 1 {
+		return hosts[0], nil
+	}
+	load := make(map[string]int64, len(hosts))
+	containers, err := d.repo.GetBotsByExecutor(ctx, d.owner.ExecutorID())
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return "", err
+	}
+	for _, c := range containers {
+		host := c.DockerHost
+		if host == "" {
+			host = hosts[0]
+		}
+		load[host]++
+	}
+	candidates := make([]placement.Candidate, 0, len(hosts))
+	for _, h := range hosts {
+		candidates = append(candidates, placement.Candidate{ID: h, Load: load[h]})
+	}
+	return d.hostStrategy.Place(bot_id, candidates)
+}
+
+func (d *DockerService) Check(ctx context.Context) error {
+	return d.runtime.Check(ctx)
+}
+
 func (d *DockerService) StopAllContainers(ctx context.Context) error {
 	fmt.Println("stopping all containers...")
-	containers, err := d.repo.GetAllBots(ctx)
+	containers, err := d.repo.GetBotsByExecutor(ctx, d.owner.ExecutorID())
 	if err != nil {
+		if errors.Is(err, ports.ErrBotsNotFound) {
+			fmt.Println("no containers to stop")
+			return nil
+		}
 		return err
 	}
-	/*TODO ERR GROUP*/
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(d.cfg.Shutdown.StopConcurrency)
 	for _, c := range containers {
-		if err := d.client.ContainerStop(ctx, c.ContainerID, container.StopOptions{Timeout: &d.cfg.Docker.Timeout}); err != nil {
-			return err
-		}
-		if err := d.repo.StopBotState(ctx, c.Id, c.BotID); err != nil {
-			return err
-		}
-		fmt.Printf("[%s] container stopped\n", c.ContainerID)
+		g.Go(func() error {
+			if err := d.stopLocked(ctx, c); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("[%s] %w", c.ContainerID, err))
+				mu.Unlock()
+				return nil
+			}
+			fmt.Printf("[%s] container stopped\n", c.ContainerID)
+			return nil
+		})
+	}
+	g.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d containers failed to stop: %w", len(errs), len(containers), errors.Join(errs...))
 	}
 	fmt.Println("all containers stopped")
 	return nil
 }
 
-func (d *DockerService) DockerFactory(message models.BotMessage) error {
+// HandOffContainers removes the container of every bot owned by this
+// executor, releases its row and publishes a run message for it so a live
+// executor recreates it. The executor must be
+// marked as draining first so placement skips it.
+func (d *DockerService) HandOffContainers(ctx context.Context, publish func(context.Context, models.BotMessage) error) error {
+	fmt.Println("handing off containers...")
+	containers, err := d.repo.GetBotsByExecutor(ctx, d.owner.ExecutorID())
+	if err != nil {
+		if errors.Is(err, ports.ErrBotsNotFound) {
+			fmt.Println("no containers to hand off")
+			return nil
+		}
+		return err
+	}
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(d.cfg.Shutdown.StopConcurrency)
+	for _, c := range containers {
+		g.Go(func() error {
+			if err := d.handOff(ctx, c, publish); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("[%s] %w", c.ContainerID, err))
+				mu.Unlock()
+				return nil
+			}
+			fmt.Printf("[%s] container handed off\n", c.ContainerID)
+			return nil
+		})
+	}
+	g.Wait()
+	if len(errs) > 0 {
+		return fmt.Errorf("%d of %d containers failed to hand off: %w", len(errs), len(containers), errors.Join(errs...))
+	}
+	fmt.Println("all containers handed off")
+	return nil
+}
+
+func (d *DockerService) handOff(ctx context.Context, c dto.ContainerDbo, publish func(context.Context, models.BotMessage) error) error {
+	unlock, err := d.repo.LockBot(ctx, c.BotID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := d.stopContainer(ctx, c.ToValue()); err != nil && !d.runtime.IsNotFound(err) {
+		return err
+	}
+	if err := d.runtime.Remove(ctx, c.ToValue()); err != nil && !d.runtime.IsNotFound(err) {
+		return err
+	}
+	// the row stays until the successor adopts it and finds the container
+	// gone; released rows are republished by ReassignOrphans if this is lost
+	if err := d.repo.SetBotExecutor(ctx, c.Id, ""); err != nil {
+		return err
+	}
+	return publish(ctx, runMessage(fmt.Sprintf("handoff:%s", d.owner.ExecutorID()), c))
+}
+
+// runMessage asks whichever executor places the bot to run it from its row.
+// The image is not sent: the successor recreates the bot from the row's
+// pinned image, and run messages from outside never choose one.
+func runMessage(prefix string, c dto.ContainerDbo) models.BotMessage {
+	return models.BotMessage{
+		ID:   fmt.Sprintf("%s:%d:%d", prefix, c.BotID, time.Now().UnixNano()),
+		Type: string(models.RUN),
+		Payload: models.BotPayload{
+			BotID:         c.BotID,
+			ProjectID:     c.ProjectID,
+			UserID:        c.UserID,
+			Name:          c.Name,
+			Description:   c.Description,
+			Icon:          c.Icon,
+			ApiToken:      c.ApiToken,
+			ApiTokenKeyID: c.ApiTokenKeyID,
+			Kind:          c.Kind,
+			Telegram:      c.ToValue().Telegram,
+		},
+	}
+}
+
+// ReassignOrphans republishes running bots whose executor stopped
+// heartbeating without handing them off, and handed off bots no executor
+// has adopted yet. Owns places them on a live executor, which adopts the
+// row. It runs as a leader job.
+func (d *DockerService) ReassignOrphans(ctx context.Context, publish func(context.Context, models.BotMessage) error) error {
+	bots, err := d.repo.GetAllBots(ctx)
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return err
+	}
+	alive := make(map[string]bool)
+	var errs []error
+	for _, c := range bots {
+		if c.State != "running" {
+			continue
+		}
+		ok, checked := alive[c.ExecutorID]
+		if !checked && c.ExecutorID != "" {
+			if ok, err = d.owner.ExecutorAlive(ctx, c.ExecutorID); err != nil {
+				return err
+			}
+			alive[c.ExecutorID] = ok
+		}
+		if ok {
+			continue
+		}
+		if err := publish(ctx, runMessage("reassign", c)); err != nil {
+			errs = append(errs, fmt.Errorf("[bot_id: %d] %w", c.BotID, err))
+			continue
+		}
+		fmt.Printf("[bot_id: %d] executor %q is gone, reassigning\n", c.BotID, c.ExecutorID)
+	}
+	return errors.Join(errs...)
+}
+
+func (d *DockerService) stopLocked(ctx context.Context, c dto.ContainerDbo) error {
+	unlock, err := d.repo.LockBot(ctx, c.BotID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := d.stopContainer(ctx, c.ToValue()); err != nil {
+		return err
+	}
+	return d.repo.StopBotState(ctx, c.Id, c.BotID)
+}
+
+func (d *DockerService) owns(ctx context.Context, payload models.BotPayload) (bool, error) {
+	existing, err := d.repo.GetContainerByBotInfo(ctx, dto.ContainerDbo{
+		BotID:     payload.BotID,
+		ProjectID: payload.ProjectID,
+		UserID:    payload.UserID,
+	})
+	if err != nil {
+		if !errors.Is(err, ports.ErrBotNotFound) {
+			return false, err
+		}
+		existing = nil
+	}
+	owned, err := d.owner.Owns(ctx, payload.BotID, existing)
+	if err != nil || !owned {
+		return false, err
+	}
+	// adopt rows created before sharding and rows left by an executor that
+	// stopped heartbeating; the caller holds the bot lock
+	if existing != nil && existing.ExecutorID != d.owner.ExecutorID() {
+		if existing.ExecutorID != "" {
+			fmt.Printf("[bot_id: %d] executor %s is gone, adopting\n", existing.BotID, existing.ExecutorID)
+		}
+		if err := d.repo.SetBotExecutor(ctx, existing.Id, d.owner.ExecutorID()); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (d *DockerService) DockerFactory(ctx context.Context, message models.BotMessage) error {
+	if message.Broadcast() {
+		ctx, cancel := withTimeout(ctx, d.cfg.Upgrade.Timeout)
+		defer cancel()
+		switch models.BotMessageType(message.Type) {
+		case models.UPGRADE:
+			return d.UpgradeContainers(ctx, message.Upgrade)
+		case models.ROLLBACK:
+			return d.RollbackContainers(ctx, message.Upgrade)
+		case models.CANARY_PROMOTE:
+			return d.PromoteCanary(ctx, message.Upgrade)
+		case models.CANARY_ABORT:
+			return d.AbortCanary(ctx, message.Upgrade)
+		}
+	}
+	ctx, cancel := withTimeout(ctx, d.cfg.Docker.MessageTimeout)
+	defer cancel()
+	unlock, err := d.repo.LockBot(ctx, message.Payload.BotID)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			fmt.Printf("[bot_id: %d] could not release lock: %s\n", message.Payload.BotID, err.Error())
+		}
+	}()
+	owned, err := d.owns(ctx, message.Payload)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return fmt.Errorf("%w: bot_id: %d", ports.ErrNotOwner, message.Payload.BotID)
+	}
 	switch message.Type {
 	case "run":
 		fmt.Println("Running container...")
-		/*if err := d.PullImage(d.ctx, d.cfg.Docker.ImageName); err != nil {
+		kind, botKind, err := d.validatePayload(message.Payload)
+		if err != nil {
 			return err
-		}*/
+		}
+		token, tokenKeyID := message.Payload.ApiToken, message.Payload.ApiTokenKeyID
+		if tokenKeyID == "" {
+			if token, tokenKeyID, err = d.tokens.Encrypt(token); err != nil {
+				return err
+			}
+		} else if !d.tokens.Knows(tokenKeyID) {
+			// reject a token this executor could never start the bot with
+			return fmt.Errorf("%w: bot_id: %d: api_token: %s", tokencrypt.ErrUnknownKey, message.Payload.BotID, tokenKeyID)
+		}
 		model := models.Container{
-			ContainerName: d.PrepareContainerName(fmt.Sprintf("%s%s", transliterateRussian(message.Payload.Name), time.Now())),
+			ContainerName: d.containerName(botKind.NamePrefix, message.Payload.Name),
+			Kind:          kind,
 			BotID:         message.Payload.BotID,
 			ProjectID:     message.Payload.ProjectID,
 			UserID:        message.Payload.UserID,
 			Name:          message.Payload.Name,
 			Description:   message.Payload.Description,
 			Icon:          message.Payload.Icon,
-			ApiToken:      message.Payload.ApiToken,
+			ApiToken:      token,
+			ApiTokenKeyID: tokenKeyID,
+			Telegram:      message.Payload.Telegram,
 			State:         "created",
+			ExecutorID:    d.owner.ExecutorID(),
 		}
-		fmt.Println(model)
-		bot, err := d.GetContainerByBotInfo(d.ctx, model)
+		bot, err := d.GetContainerByBotInfo(ctx, model)
 		if err != nil {
-			resp, db_id, err := d.CreateContainer(d.ctx, model)
+			bot, err = d.CreateContainer(ctx, model)
 			if err != nil {
 				return err
 			}
-			if err := d.RunContainer(d.ctx, resp.ID, db_id); err != nil {
+			if err := d.RunContainer(ctx, *bot); err != nil {
 				return err
 			}
-			if err := d.GetContainerLogs(d.ctx, resp.ID); err != nil {
-				return err
-			}
-			fmt.Printf("[%s] container running\n", resp.ID)
 		} else {
 			fmt.Println("container already exists")
-			if err := d.RunContainer(d.ctx, bot.ContainerID, bot.Id); err != nil {
-				if strings.Contains(err.Error(), "No such container") {
-					if err := d.repo.StopBotState(d.ctx, bot.Id, bot.BotID); err != nil {
-						return err
-					}
-					if err := d.repo.DeleteBotById(d.ctx, bot.Id); err != nil {
-						return err
-					}
-					resp, db_id, err := d.CreateContainer(d.ctx, model)
-					if err != nil {
-						return err
-					}
-					if err := d.RunContainer(d.ctx, resp.ID, db_id); err != nil {
-						return err
-					}
-					if err := d.GetContainerLogs(d.ctx, resp.ID); err != nil {
-						return err
-					}
-				} else {
+			if err := d.RunContainer(ctx, *bot); err != nil {
+				if !d.runtime.IsNotFound(err) {
+					return err
+				}
+				// the container may still exist, e.g. without its secret files
+				if err := d.runtime.Remove(ctx, *bot); err != nil && !d.runtime.IsNotFound(err) {
+					fmt.Printf("[%s] could not remove container: %s\n", bot.ContainerID, err.Error())
+				}
+				if err := d.repo.StopBotState(ctx, bot.Id, bot.BotID); err != nil {
+					return err
+				}
+				if err := d.repo.DeleteBotById(ctx, bot.Id); err != nil {
+					return err
+				}
+				// recreate from the pinned image rather than the current tag
+				model.Image = bot.Image
+				model.Variant = bot.Variant
+				bot, err = d.CreateContainer(ctx, model)
+				if err != nil {
+					return err
+				}
+				if err := d.RunContainer(ctx, *bot); err != nil {
 					return err
 				}
 			}
-			if err := d.GetContainerLogs(d.ctx, bot.ContainerID); err != nil {
-				return err
-			}
-			fmt.Printf("[%s] container running\n", bot.ContainerID)
 		}
+		// a bot that was just started may not have a pod or output yet
+		if err := d.GetContainerLogs(ctx, *bot); err != nil {
+			fmt.Printf("[%s] could not fetch logs: %s\n", bot.ContainerID, err.Error())
+		}
+		fmt.Printf("[%s] container running\n", bot.ContainerID)
 	case "stop":
 		fmt.Println("Stopping container...")
 		model := models.Container{
-			ContainerID: d.PrepareContainerName(fmt.Sprintf("%s%s", transliterateRussian(message.Payload.Name), time.Now())),
+			Kind:        message.Payload.Kind,
 			BotID:       message.Payload.BotID,
 			ProjectID:   message.Payload.ProjectID,
 			UserID:      message.Payload.UserID,
@@ -264,7 +566,7 @@ func (d *DockerService) DockerFactory(message models.BotMessage) error {
 			Description: message.Payload.Description,
 			Icon:        message.Payload.Icon,
 		}
-		if err := d.StopContainer(d.ctx, model); err != nil {
+		if err := d.StopContainer(ctx, model); err != nil {
 			return err
 		}
 		fmt.Println("container stopped")
@@ -272,7 +574,7 @@ func (d *DockerService) DockerFactory(message models.BotMessage) error {
 	return nil
 }
 
-func (d *DockerService) PrepareContainerName(str string) string {
+func (d *DockerService) PrepareContainerName(prefix, str string) string {
 	r1 := strings.NewReplacer(
 		" ", "",
 		"-", "",
@@ -306,7 +608,7 @@ func (d *DockerService) PrepareContainerName(str string) string {
 	)
 	clean_str := r1.Replace(str)
 	clean_str = strings.ToLower(clean_str)
-	return fmt.Sprintf("tg-%s", clean_str)
+	return fmt.Sprintf("%s%s", prefix, clean_str)
 }
 
 var translitMap = map[rune]string{
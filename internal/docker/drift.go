@@ -0,0 +1,127 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/ports"
+	"fmt"
+	"time"
+)
+
+const (
+	DriftReport   = "report"
+	DriftRecreate = "recreate"
+)
+
+type Drift struct {
+	BotID       int64  `json:"bot_id"`
+	ProjectID   int64  `json:"project_id"`
+	UserID      int64  `json:"user_id"`
+	ContainerID string `json:"container_id"`
+	ExecutorID  string `json:"executor_id"`
+	State       string `json:"state"`
+	Image       string `json:"image"`
+	Variant     string `json:"image_variant"`
+	StoredHash  string `json:"stored_hash"`
+	CurrentHash string `json:"current_hash"`
+	// Error is set instead of CurrentHash when the spec could not be built
+	Error string `json:"error,omitempty"`
+}
+
+// DetectDrift rebuilds the spec of every bot from the current config and
+// returns those whose hash no longer matches. Rows created before hashing
+// carry no hash and always count as drifted. A bot whose spec cannot be
+// built is returned with Error set and does not stop the others.
+func (d *DockerService) DetectDrift(ctx context.Context, bots []dto.ContainerDbo) ([]Drift, error) {
+	drifted := make([]Drift, 0)
+	for _, c := range bots {
+		drift := Drift{
+			BotID:       c.BotID,
+			ProjectID:   c.ProjectID,
+			UserID:      c.UserID,
+			ContainerID: c.ContainerID,
+			ExecutorID:  c.ExecutorID,
+			State:       c.State,
+			Image:       c.Image,
+			Variant:     c.Variant,
+			StoredHash:  c.SpecHash,
+		}
+		spec, err := d.CreateContainerConfig(ctx, c.ToValue())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			drift.Error = err.Error()
+			drifted = append(drifted, drift)
+			continue
+		}
+		drift.CurrentHash = spec.Hash()
+		if drift.CurrentHash == c.SpecHash {
+			continue
+		}
+		drifted = append(drifted, drift)
+	}
+	return drifted, nil
+}
+
+func (d *DockerService) ownBots(ctx context.Context) ([]dto.ContainerDbo, error) {
+	bots, err := d.repo.GetBotsByExecutor(ctx, d.owner.ExecutorID())
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return nil, err
+	}
+	return bots, nil
+}
+
+// WatchDrift checks this executor's bots for drift every interval and, with
+// the recreate policy, recreates drifted running bots on their current image.
+func (d *DockerService) WatchDrift(ctx context.Context) {
+	if d.cfg.Drift.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(d.cfg.Drift.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.checkDrift(ctx); err != nil {
+				fmt.Printf("[drift] %s\n", err.Error())
+			}
+		}
+	}
+}
+
+func (d *DockerService) checkDrift(ctx context.Context) error {
+	bots, err := d.ownBots(ctx)
+	if err != nil {
+		return err
+	}
+	drifted, err := d.DetectDrift(ctx, bots)
+	if err != nil {
+		return err
+	}
+	if len(drifted) == 0 {
+		return nil
+	}
+	byBot := make(map[int64]bool, len(drifted))
+	for _, drift := range drifted {
+		if drift.Error != "" {
+			fmt.Printf("[drift] bot_id: %d (%s) could not build spec: %s\n", drift.BotID, drift.ContainerID, drift.Error)
+			continue
+		}
+		byBot[drift.BotID] = true
+		fmt.Printf("[drift] bot_id: %d (%s) spec changed: %s -> %s\n", drift.BotID, drift.ContainerID, drift.StoredHash, drift.CurrentHash)
+	}
+	if d.cfg.Drift.Policy != DriftRecreate {
+		return nil
+	}
+	tasks := make([]upgradeTask, 0, len(drifted))
+	for _, c := range bots {
+		if byBot[c.BotID] && c.State == "running" {
+			tasks = append(tasks, upgradeTask{bot: c, image: c.Image, previous: c.PreviousImage})
+		}
+	}
+	return d.rollout(ctx, "drift", tasks, d.upgradeOptions(nil))
+}
@@ -0,0 +1,45 @@
+package docker
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/envtemplate"
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	cfg := &config.ExecutorConfig{
+		SearchUrl: "http://search:8080",
+		BotKinds:  map[string]config.BotKind{config.DefaultBotKind: {Image: "bot"}},
+	}
+	env, _ := envtemplate.New(nil, cfg)
+	d := &DockerService{repo: &fakeRepo{}, cfg: cfg, env: map[string]*envtemplate.EnvTemplate{config.DefaultBotKind: env}}
+	ctx := context.Background()
+	bot := dto.ContainerDbo{BotID: 1, Image: "bot@sha256:1", State: "running"}
+
+	spec, err := d.CreateContainerConfig(ctx, bot.ToValue())
+	if err != nil {
+		t.Fatalf("CreateContainerConfig() error = %v", err)
+	}
+	bot.SpecHash = spec.Hash()
+	if drifted, _ := d.DetectDrift(ctx, []dto.ContainerDbo{bot}); len(drifted) != 0 {
+		t.Errorf("DetectDrift() = %v, want no drift", drifted)
+	}
+
+	cfg.SearchUrl = "http://search-v2:8080"
+	drifted, _ := d.DetectDrift(ctx, []dto.ContainerDbo{bot})
+	if len(drifted) != 1 || drifted[0].StoredHash != bot.SpecHash {
+		t.Errorf("DetectDrift() = %v, want bot_id 1 drifted", drifted)
+	}
+
+	// a bot whose spec cannot be built is reported without hiding the others
+	broken := dto.ContainerDbo{BotID: 2, Kind: "unknown", State: "running"}
+	drifted, err = d.DetectDrift(ctx, []dto.ContainerDbo{broken, bot})
+	if err != nil {
+		t.Fatalf("DetectDrift() error = %v", err)
+	}
+	if len(drifted) != 2 || drifted[0].BotID != 2 || drifted[0].Error == "" || drifted[1].BotID != 1 {
+		t.Errorf("DetectDrift() = %v, want an error entry for bot_id 2 and bot_id 1 drifted", drifted)
+	}
+}
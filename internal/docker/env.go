@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/models"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidEnvKey = errors.New("invalid env variable name")
+	ErrProtectedEnv  = errors.New("env variable cannot be overridden")
+)
+
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func (d *DockerService) BotEnv(ctx context.Context, botID int64) ([]models.EnvOverride, error) {
+	rows, err := d.repo.GetBotEnv(ctx, botID)
+	if err != nil {
+		return nil, err
+	}
+	overrides := make([]models.EnvOverride, 0, len(rows))
+	for _, row := range rows {
+		overrides = append(overrides, row.ToValue())
+	}
+	return overrides, nil
+}
+
+// SetBotEnv stores an override; it reaches the bot the next time its
+// container is created, which drift detection reports.
+func (d *DockerService) SetBotEnv(ctx context.Context, botID int64, override models.EnvOverride) error {
+	if !envKey.MatchString(override.Key) {
+		return fmt.Errorf("%w: %q", ErrInvalidEnvKey, override.Key)
+	}
+	if d.cfg.Container.Protected(override.Key, d.cfg.Telegram) {
+		return fmt.Errorf("%w: %s", ErrProtectedEnv, override.Key)
+	}
+	return d.repo.SetBotEnv(ctx, dto.BotEnvDbo{
+		BotID:  botID,
+		Key:    override.Key,
+		Value:  override.Value,
+		Secret: override.Secret,
+	})
+}
+
+func (d *DockerService) UnsetBotEnv(ctx context.Context, botID int64, key string) error {
+	return d.repo.DeleteBotEnv(ctx, botID, key)
+}
+
+// applyEnvOverrides replaces variables of env in place and appends new ones.
+// Overrides stored before a key became protected are skipped.
+func (d *DockerService) applyEnvOverrides(botID int64, env []string, overrides []models.EnvOverride) []string {
+	index := make(map[string]int, len(env))
+	for i, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		index[k] = i
+	}
+	for _, o := range overrides {
+		if d.cfg.Container.Protected(o.Key, d.cfg.Telegram) {
+			fmt.Printf("[bot_id: %d] env override %s is protected, skipping\n", botID, o.Key)
+			continue
+		}
+		if i, ok := index[o.Key]; ok {
+			env[i] = o.Key + "=" + o.Value
+			continue
+		}
+		index[o.Key] = len(env)
+		env = append(env, o.Key+"="+o.Value)
+	}
+	return env
+}
@@ -0,0 +1,50 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"reflect"
+	"testing"
+)
+
+func TestApplyEnvOverrides(t *testing.T) {
+	d := &DockerService{cfg: &config.ExecutorConfig{
+		Container: config.Container{ProtectedEnv: config.DefaultProtectedEnv},
+		Telegram:  config.Telegram{EnvName: "TELEGRAM_SETTINGS"},
+	}}
+	env := []string{"CONTAINER_BOT_ID=1", "OPEN_ROUTER_API_MODEL=default"}
+	got := d.applyEnvOverrides(1, env, []models.EnvOverride{
+		{Key: "OPEN_ROUTER_API_MODEL", Value: "custom"},
+		{Key: "CONTAINER_BOT_ID", Value: "2"},
+		{Key: "FEATURE_VOICE", Value: "true"},
+	})
+	want := []string{"CONTAINER_BOT_ID=1", "OPEN_ROUTER_API_MODEL=custom", "FEATURE_VOICE=true"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyEnvOverrides() = %v, want %v", got, want)
+	}
+}
+
+func TestSetBotEnv(t *testing.T) {
+	repo := &fakeRepo{}
+	d := &DockerService{repo: repo, cfg: &config.ExecutorConfig{
+		Container: config.Container{ProtectedEnv: config.DefaultProtectedEnv},
+		Telegram:  config.Telegram{EnvName: "TELEGRAM_SETTINGS"},
+	}}
+	ctx := context.Background()
+	for key, want := range map[string]error{
+		"TELEGRAM_BOT_TOKEN":     ErrProtectedEnv,
+		"POSTGRES_HOST":          ErrProtectedEnv,
+		"TELEGRAM_SETTINGS_FILE": ErrProtectedEnv,
+		"1BAD":                   ErrInvalidEnvKey,
+		"OPEN_ROUTER_API_MODEL":  nil,
+	} {
+		if err := d.SetBotEnv(ctx, 1, models.EnvOverride{Key: key, Value: "v"}); !errors.Is(err, want) {
+			t.Errorf("SetBotEnv(%s) error = %v, want %v", key, err, want)
+		}
+	}
+	if len(repo.env) != 1 || repo.env[0].Key != "OPEN_ROUTER_API_MODEL" {
+		t.Errorf("SetBotEnv() stored %v, want only OPEN_ROUTER_API_MODEL", repo.env)
+	}
+}
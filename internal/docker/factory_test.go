@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"context"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/kubernetes"
+	"executor/internal/tokencrypt"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRunOnKubernetes(t *testing.T) {
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	repo := &fakeRepo{}
+	d := newRolloutService(t, repo, nil)
+	d.runtime = kubernetes.NewKubernetesRuntimeWithClient(clientset, &config.ExecutorConfig{Kubernetes: config.Kubernetes{Namespace: "bots"}})
+	d.canary = &fakeCanary{kind: config.DefaultBotKind}
+	d.tokens, _ = tokencrypt.New(config.TokenEncryption{})
+
+	// the pod does not exist yet right after the deployment is scaled up
+	err := d.DockerFactory(ctx, models.BotMessage{
+		Type:    string(models.RUN),
+		Payload: models.BotPayload{BotID: 1, Name: "support", ApiToken: "token"},
+	})
+	if err != nil {
+		t.Fatalf("DockerFactory() error = %v", err)
+	}
+	rows := repo.live(1)
+	if len(rows) != 1 || rows[0].State != "running" || rows[0].Image != "bot:2" {
+		t.Fatalf("bot_id 1 rows = %+v, want one running row on bot:2", rows)
+	}
+	deployment, err := clientset.AppsV1().Deployments("bots").Get(ctx, rows[0].ContainerID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Deployments.Get() error = %v", err)
+	}
+	if *deployment.Spec.Replicas != 1 {
+		t.Errorf("replicas = %d, want 1", *deployment.Spec.Replicas)
+	}
+}
@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"fmt"
+	"io"
+	"time"
+)
+
+// fakeRepo keeps rows in memory; repository calls it does not implement panic.
+type fakeRepo struct {
+	ports.ContainersRepository
+	bots []dto.ContainerDbo
+	env  []dto.BotEnvDbo
+}
+
+func (r *fakeRepo) GetAllBots(ctx context.Context) ([]dto.ContainerDbo, error) {
+	if len(r.bots) == 0 {
+		return nil, ports.ErrBotsNotFound
+	}
+	return r.bots, nil
+}
+
+func (r *fakeRepo) GetBotEnv(ctx context.Context, bot_id int64) ([]dto.BotEnvDbo, error) {
+	var rows []dto.BotEnvDbo
+	for _, e := range r.env {
+		if e.BotID == bot_id {
+			rows = append(rows, e)
+		}
+	}
+	return rows, nil
+}
+
+func (r *fakeRepo) SetBotEnv(ctx context.Context, env dto.BotEnvDbo) error {
+	r.env = append(r.env, env)
+	return nil
+}
+
+type fakeOwner struct {
+	id   string
+	live map[string]bool
+}
+
+func (o *fakeOwner) ExecutorID() string {
+	return o.id
+}
+
+func (o *fakeOwner) ExecutorAlive(ctx context.Context, id string) (bool, error) {
+	return o.live[id], nil
+}
+
+func (o *fakeOwner) Owns(ctx context.Context, bot_id int64, existing *dto.ContainerDbo) (bool, error) {
+	return existing == nil || existing.ExecutorID == "" || existing.ExecutorID == o.id, nil
+}
+
+func (r *fakeRepo) row(id int64) *dto.ContainerDbo {
+	for i := range r.bots {
+		if r.bots[i].Id == id {
+			return &r.bots[i]
+		}
+	}
+	return nil
+}
+
+// live returns the rows of a bot that are not retired.
+func (r *fakeRepo) live(bot_id int64) []dto.ContainerDbo {
+	var rows []dto.ContainerDbo
+	for _, c := range r.bots {
+		if c.BotID == bot_id && !c.DeletedAt.Valid {
+			rows = append(rows, c)
+		}
+	}
+	return rows
+}
+
+func (r *fakeRepo) LockBot(ctx context.Context, bot_id int64) (func() error, error) {
+	return func() error { return nil }, nil
+}
+
+func (r *fakeRepo) GetContainerById(ctx context.Context, id int64) (*dto.ContainerDbo, error) {
+	c := r.row(id)
+	if c == nil || c.DeletedAt.Valid {
+		return nil, ports.ErrBotNotFound
+	}
+	res := *c
+	return &res, nil
+}
+
+func (r *fakeRepo) GetBotsByExecutor(ctx context.Context, executor_id string) ([]dto.ContainerDbo, error) {
+	var rows []dto.ContainerDbo
+	for _, c := range r.bots {
+		if c.ExecutorID == executor_id && !c.DeletedAt.Valid {
+			rows = append(rows, c)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, ports.ErrBotsNotFound
+	}
+	return rows, nil
+}
+
+func (r *fakeRepo) CreateBot(ctx context.Context, bot dto.ContainerDbo) (int64, error) {
+	var id int64
+	for _, c := range r.bots {
+		id = max(id, c.Id)
+	}
+	bot.Id = id + 1
+	r.bots = append(r.bots, bot)
+	return bot.Id, nil
+}
+
+func (r *fakeRepo) SetBotState(ctx context.Context, state string, id int64) error {
+	if c := r.row(id); c != nil {
+		c.State = state
+	}
+	return nil
+}
+
+func (r *fakeRepo) RetireBotById(ctx context.Context, id int64) error {
+	if c := r.row(id); c != nil {
+		c.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (r *fakeRepo) RestoreBotById(ctx context.Context, id int64) error {
+	if c := r.row(id); c != nil {
+		c.DeletedAt = sql.NullTime{}
+	}
+	return nil
+}
+
+func (r *fakeRepo) DeleteBotById(ctx context.Context, id int64) error {
+	for i, c := range r.bots {
+		if c.Id == id {
+			r.bots = append(r.bots[:i], r.bots[i+1:]...)
+			return nil
+		}
+	}
+	return ports.ErrBotNotFound
+}
+
+var errFakeStart = errors.New("container exited")
+
+// fakeRuntime runs nothing; starting a container on a broken image fails.
+type fakeRuntime struct {
+	broken  map[string]bool
+	created int
+}
+
+func (f *fakeRuntime) Name() string {
+	return "fake"
+}
+
+func (f *fakeRuntime) Hosts() []string {
+	return []string{"default"}
+}
+
+func (f *fakeRuntime) Create(ctx context.Context, bot models.Container, spec models.ContainerSpec) (*models.Container, error) {
+	f.created++
+	bot.ContainerID = fmt.Sprintf("c%d", f.created)
+	return &bot, nil
+}
+
+func (f *fakeRuntime) Start(ctx context.Context, bot models.Container) error {
+	if f.broken[bot.Image] {
+		return errFakeStart
+	}
+	return nil
+}
+
+func (f *fakeRuntime) Stop(ctx context.Context, bot models.Container) error {
+	return nil
+}
+
+func (f *fakeRuntime) Remove(ctx context.Context, bot models.Container) error {
+	return nil
+}
+
+func (f *fakeRuntime) Logs(ctx context.Context, bot models.Container, w io.Writer) error {
+	return nil
+}
+
+func (f *fakeRuntime) Check(ctx context.Context) error {
+	return nil
+}
+
+func (f *fakeRuntime) IsNotFound(err error) bool {
+	return false
+}
+
+func (r *fakeRepo) SetBotExecutor(ctx context.Context, id int64, executor_id string) error {
+	if c := r.row(id); c != nil {
+		c.ExecutorID = executor_id
+	}
+	return nil
+}
+
+func (r *fakeRepo) SetBotImage(ctx context.Context, id int64, image, previous_image, variant string) error {
+	if c := r.row(id); c != nil {
+		c.Image, c.PreviousImage, c.Variant = image, previous_image, variant
+	}
+	return nil
+}
+
+type fakeCanary struct {
+	ports.Canary
+	kind string
+}
+
+func (c *fakeCanary) Kind() string {
+	return c.kind
+}
+
+func (c *fakeCanary) SelectImage(ctx context.Context, bot models.Container) (string, string, error) {
+	return "bot:2", models.VariantStable, nil
+}
+
+func (c *fakeCanary) Abort(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeRepo) GetContainerByBotInfo(ctx context.Context, bot dto.ContainerDbo) (*dto.ContainerDbo, error) {
+	for _, c := range r.bots {
+		if c.BotID == bot.BotID && !c.DeletedAt.Valid {
+			res := c
+			return &res, nil
+		}
+	}
+	return nil, ports.ErrBotNotFound
+}
@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+)
+
+var (
+	ErrImagePull       = errors.New("could not pull image")
+	ErrImageNotPresent = errors.New("image is not present and pull policy is never")
+)
+
+const (
+	PullAlways       = "always"
+	PullIfNotPresent = "if-not-present"
+	PullNever        = "never"
+)
+
+func (r *DockerRuntime) EnsureImage(ctx context.Context, host, img string) error {
+	cli, err := r.clientFor(host)
+	if err != nil {
+		return err
+	}
+	if r.cfg.Docker.PullPolicy != PullAlways {
+		_, _, err := cli.ImageInspectWithRaw(ctx, img)
+		if err == nil {
+			return nil
+		}
+		if !client.IsErrNotFound(err) {
+			return err
+		}
+		if r.cfg.Docker.PullPolicy == PullNever {
+			return fmt.Errorf("%w: %s on %s", ErrImageNotPresent, img, hostOrDefault(host))
+		}
+	}
+	return r.PullImage(ctx, host, img)
+}
+
+// ResolveImage pins img to the registry digest of the local copy. Images
+// without a registry digest (built locally) keep their tag: an image ID only
+// exists on this host and could not be pulled on another one or after a
+// handoff.
+func (r *DockerRuntime) ResolveImage(ctx context.Context, host, img string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return img, nil
+	}
+	cli, err := r.clientFor(host)
+	if err != nil {
+		return "", err
+	}
+	inspect, _, err := cli.ImageInspectWithRaw(ctx, img)
+	if err != nil {
+		return "", err
+	}
+	if pinned, ok := repoDigest(named, inspect.RepoDigests); ok {
+		return pinned, nil
+	}
+	fmt.Printf("[%s] %s has no registry digest, keeping the tag unpinned\n", hostOrDefault(host), img)
+	return img, nil
+}
+
+func repoDigest(named reference.Named, digests []string) (string, bool) {
+	for _, d := range digests {
+		canonical, err := reference.ParseNormalizedNamed(d)
+		if err != nil {
+			continue
+		}
+		if canonical.Name() == named.Name() {
+			return d, true
+		}
+	}
+	return "", false
+}
+
+func (r *DockerRuntime) PullImage(ctx context.Context, host, img string) error {
+	cli, err := r.clientFor(host)
+	if err != nil {
+		return err
+	}
+	auth, err := registryAuth(r.cfg.Docker.Registries, img)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("[%s] pulling %s\n", hostOrDefault(host), img)
+	reader, err := cli.ImagePull(ctx, img, image.PullOptions{RegistryAuth: auth})
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrImagePull, img, err)
+	}
+	defer reader.Close()
+	if err := logPullProgress(reader, img); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrImagePull, img, err)
+	}
+	fmt.Printf("[%s] pulled %s\n", hostOrDefault(host), img)
+	return nil
+}
+
+// logPullProgress decodes the daemon's JSON progress stream and logs every
+// status change per layer rather than each byte count update.
+func logPullProgress(r io.Reader, img string) error {
+	decoder := json.NewDecoder(r)
+	layers := make(map[string]string)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if msg.ErrorMessage != "" {
+			return errors.New(msg.ErrorMessage)
+		}
+		if msg.ID == "" {
+			if msg.Status != "" {
+				fmt.Printf("[%s] %s\n", img, msg.Status)
+			}
+			continue
+		}
+		if layers[msg.ID] == msg.Status {
+			continue
+		}
+		layers[msg.ID] = msg.Status
+		fmt.Printf("[%s] layer %s: %s\n", img, msg.ID, msg.Status)
+	}
+}
+
+func hostOrDefault(host string) string {
+	if host == "" {
+		return defaultHostName
+	}
+	return host
+}
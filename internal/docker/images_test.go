@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/distribution/reference"
+)
+
+func TestLogPullProgress(t *testing.T) {
+	stream := `{"status":"Pulling from library/alpine","id":"latest"}
+{"status":"Pulling fs layer","id":"a1"}
+{"status":"Downloading","progressDetail":{"current":10,"total":100},"id":"a1"}
+{"status":"Downloading","progressDetail":{"current":50,"total":100},"id":"a1"}
+{"status":"Pull complete","id":"a1"}
+{"status":"Digest: sha256:abc"}
+`
+	if err := logPullProgress(strings.NewReader(stream), "alpine"); err != nil {
+		t.Errorf("logPullProgress() error = %v", err)
+	}
+}
+
+func TestLogPullProgressError(t *testing.T) {
+	stream := `{"status":"Pulling fs layer","id":"a1"}
+{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}
+`
+	err := logPullProgress(strings.NewReader(stream), "private/bot")
+	if err == nil || !strings.Contains(err.Error(), "unauthorized") {
+		t.Errorf("logPullProgress() error = %v, want unauthorized", err)
+	}
+}
+
+func TestRepoDigest(t *testing.T) {
+	named, _ := reference.ParseNormalizedNamed("registry.example.com/bots/telegram:latest")
+	digests := []string{
+		"mirror.example.com/bots/telegram@sha256:1111111111111111111111111111111111111111111111111111111111111111",
+		"registry.example.com/bots/telegram@sha256:2222222222222222222222222222222222222222222222222222222222222222",
+	}
+	got, ok := repoDigest(named, digests)
+	if !ok || got != digests[1] {
+		t.Errorf("repoDigest() = %v, %v, want %v", got, ok, digests[1])
+	}
+	if _, ok := repoDigest(named, digests[:1]); ok {
+		t.Errorf("repoDigest() found a digest for another repository")
+	}
+}
@@ -0,0 +1,69 @@
+package docker
+
+import (
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnknownKind   = errors.New("unknown bot kind")
+	ErrMissingFields = errors.New("payload is missing required fields")
+)
+
+// botKind looks up a kind's config; rows and payloads without a kind are
+// telegram bots.
+func (d *DockerService) botKind(name string) (string, config.BotKind, error) {
+	if name == "" {
+		name = config.DefaultBotKind
+	}
+	kind, ok := d.cfg.BotKinds[name]
+	if !ok {
+		return "", config.BotKind{}, fmt.Errorf("%w: %s", ErrUnknownKind, name)
+	}
+	return name, kind, nil
+}
+
+func (d *DockerService) validatePayload(payload models.BotPayload) (string, config.BotKind, error) {
+	name, kind, err := d.botKind(payload.Kind)
+	if err != nil {
+		return "", config.BotKind{}, err
+	}
+	if missing := missingFields(payload, kind.RequiredFields); len(missing) > 0 {
+		return "", config.BotKind{}, fmt.Errorf("%w: %s bot_id: %d: %s", ErrMissingFields, name, payload.BotID, strings.Join(missing, ", "))
+	}
+	if payload.Telegram != nil {
+		if name != config.DefaultBotKind {
+			return "", config.BotKind{}, fmt.Errorf("%w: %s bot_id: %d: telegram settings are only for telegram bots", models.ErrInvalidTelegram, name, payload.BotID)
+		}
+		if err := d.cfg.Telegram.Settings().Merge(payload.Telegram).Validate(); err != nil {
+			return "", config.BotKind{}, fmt.Errorf("bot_id: %d: %w", payload.BotID, err)
+		}
+	}
+	return name, kind, nil
+}
+
+func missingFields(payload models.BotPayload, required []string) []string {
+	set := map[string]bool{
+		"project_id":  payload.ProjectID != 0,
+		"user_id":     payload.UserID != 0,
+		"name":        payload.Name != "",
+		"description": payload.Description != "",
+		"icon":        payload.Icon != "",
+		"api_token":   payload.ApiToken != "",
+	}
+	var missing []string
+	for _, field := range required {
+		if !set[field] {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+func (d *DockerService) containerName(prefix, name string) string {
+	return d.PrepareContainerName(prefix, fmt.Sprintf("%s%s", transliterateRussian(name), time.Now()))
+}
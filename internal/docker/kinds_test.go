@@ -0,0 +1,25 @@
+package docker
+
+import (
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"testing"
+)
+
+func TestValidatePayload(t *testing.T) {
+	d := &DockerService{cfg: &config.ExecutorConfig{BotKinds: map[string]config.BotKind{
+		config.DefaultBotKind: {Image: "tg", RequiredFields: []string{"api_token"}},
+		"webhook":             {Image: "hook", RequiredFields: []string{"name", "icon"}},
+	}}}
+
+	if kind, _, err := d.validatePayload(models.BotPayload{BotID: 1, ApiToken: "t"}); err != nil || kind != config.DefaultBotKind {
+		t.Errorf("validatePayload() = %v, %v, want %v", kind, err, config.DefaultBotKind)
+	}
+	if _, _, err := d.validatePayload(models.BotPayload{BotID: 1, Kind: "webhook", Name: "n"}); !errors.Is(err, ErrMissingFields) {
+		t.Errorf("validatePayload() error = %v, want %v", err, ErrMissingFields)
+	}
+	if _, _, err := d.validatePayload(models.BotPayload{BotID: 1, Kind: "irc"}); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("validatePayload() error = %v, want %v", err, ErrUnknownKind)
+	}
+}
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"testing"
+)
+
+func TestReassignOrphans(t *testing.T) {
+	repo := &fakeRepo{bots: []dto.ContainerDbo{
+		{BotID: 1, State: "running", ExecutorID: "a"},
+		{BotID: 2, State: "running", ExecutorID: "dead"},
+		{BotID: 3, State: "stopped", ExecutorID: "dead"},
+		{BotID: 4, State: "running"},
+	}}
+	d := &DockerService{repo: repo, owner: &fakeOwner{id: "a", live: map[string]bool{"a": true}}, cfg: &config.ExecutorConfig{}}
+	var published []models.BotMessage
+	publish := func(ctx context.Context, m models.BotMessage) error {
+		published = append(published, m)
+		return nil
+	}
+	if err := d.ReassignOrphans(context.Background(), publish); err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 2 || published[0].Payload.BotID != 2 || published[1].Payload.BotID != 4 || published[0].Type != string(models.RUN) {
+		t.Errorf("ReassignOrphans() published %+v, want run messages for bot_id 2 and 4", published)
+	}
+}
+
+func TestHandOffKeepsRowWhenPublishFails(t *testing.T) {
+	repo := &fakeRepo{bots: []dto.ContainerDbo{{Id: 1, BotID: 1, State: "running", ExecutorID: "a", Image: "bot:1"}}}
+	d := newRolloutService(t, repo, &fakeRuntime{})
+	publish := func(ctx context.Context, m models.BotMessage) error {
+		return errors.New("redis is down")
+	}
+	if err := d.HandOffContainers(context.Background(), publish); err == nil {
+		t.Fatalf("HandOffContainers() error = nil, want the publish error")
+	}
+	if rows := repo.live(1); len(rows) != 1 || rows[0].ExecutorID != "" {
+		t.Fatalf("bot_id 1 rows = %+v, want one released row", rows)
+	}
+
+	// the leader republishes the released row
+	var published []models.BotMessage
+	publish = func(ctx context.Context, m models.BotMessage) error {
+		published = append(published, m)
+		return nil
+	}
+	if err := d.ReassignOrphans(context.Background(), publish); err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 1 || published[0].Payload.BotID != 1 {
+		t.Errorf("ReassignOrphans() published %+v, want a run message for bot_id 1", published)
+	}
+}
@@ -0,0 +1,126 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"executor/internal/core/config"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/registry"
+)
+
+var ErrRegistryAuth = errors.New("could not resolve registry credentials")
+
+const dockerHubDomain = "docker.io"
+
+// registryAuth returns the encoded RegistryAuth for the registry serving img,
+// or an empty string when no credentials are configured for it. Password and
+// config files are read on every call so rotated credentials are picked up
+// without a restart.
+func registryAuth(registries []config.DockerRegistry, img string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(img)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrRegistryAuth, img, err)
+	}
+	domain := reference.Domain(named)
+	for _, reg := range registries {
+		if normalizeRegistry(reg.Server) != domain {
+			continue
+		}
+		auth, err := loadCredentials(reg)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s: %w", ErrRegistryAuth, reg.Server, err)
+		}
+		return registry.EncodeAuthConfig(auth)
+	}
+	return "", nil
+}
+
+func loadCredentials(reg config.DockerRegistry) (registry.AuthConfig, error) {
+	switch {
+	case reg.ConfigFile != "":
+		return readDockerConfig(reg.ConfigFile, reg.Server)
+	case reg.PasswordFile != "":
+		password, err := os.ReadFile(reg.PasswordFile)
+		if err != nil {
+			return registry.AuthConfig{}, err
+		}
+		return registry.AuthConfig{
+			Username:      reg.Username,
+			Password:      strings.TrimSpace(string(password)),
+			ServerAddress: reg.Server,
+		}, nil
+	default:
+		return registry.AuthConfig{
+			Username:      reg.Username,
+			Password:      reg.Password,
+			ServerAddress: reg.Server,
+		}, nil
+	}
+}
+
+type dockerConfigFile struct {
+	Auths map[string]dockerConfigAuth `json:"auths"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// readDockerConfig looks server up in the auths section of a docker
+// config.json. Credential stores and helpers are not supported.
+func readDockerConfig(path, server string) (registry.AuthConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return registry.AuthConfig{}, err
+	}
+	var file dockerConfigFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return registry.AuthConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	want := normalizeRegistry(server)
+	for key, entry := range file.Auths {
+		if normalizeRegistry(key) != want {
+			continue
+		}
+		auth := registry.AuthConfig{
+			Username:      entry.Username,
+			Password:      entry.Password,
+			IdentityToken: entry.IdentityToken,
+			ServerAddress: server,
+		}
+		if entry.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+			if err != nil {
+				return registry.AuthConfig{}, fmt.Errorf("%s: auth for %s: %w", path, key, err)
+			}
+			username, password, ok := strings.Cut(string(decoded), ":")
+			if !ok {
+				return registry.AuthConfig{}, fmt.Errorf("%s: auth for %s is not user:password", path, key)
+			}
+			auth.Username, auth.Password = username, password
+		}
+		return auth, nil
+	}
+	return registry.AuthConfig{}, fmt.Errorf("%s: no auths entry for %s", path, server)
+}
+
+// normalizeRegistry reduces a registry address to the domain used by image
+// references, so "https://index.docker.io/v1/" and "docker.io" match.
+func normalizeRegistry(server string) string {
+	server = strings.TrimPrefix(server, "https://")
+	server = strings.TrimPrefix(server, "http://")
+	server, _, _ = strings.Cut(server, "/")
+	switch server {
+	case "index.docker.io", "registry-1.docker.io":
+		return dockerHubDomain
+	}
+	return server
+}
@@ -0,0 +1,68 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"executor/internal/core/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/registry"
+)
+
+func decodeAuth(t *testing.T, encoded string) registry.AuthConfig {
+	t.Helper()
+	data, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("base64.DecodeString() error = %v", err)
+	}
+	var auth registry.AuthConfig
+	if err := json.Unmarshal(data, &auth); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	return auth
+}
+
+func TestRegistryAuthPasswordFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	os.WriteFile(path, []byte("first\n"), 0o600)
+	registries := []config.DockerRegistry{{Server: "registry.example.com", Username: "executor", PasswordFile: path}}
+
+	encoded, err := registryAuth(registries, "registry.example.com/bots/telegram:1.2")
+	if err != nil {
+		t.Fatalf("registryAuth() error = %v", err)
+	}
+	if auth := decodeAuth(t, encoded); auth.Username != "executor" || auth.Password != "first" {
+		t.Errorf("registryAuth() = %v/%v, want executor/first", auth.Username, auth.Password)
+	}
+
+	os.WriteFile(path, []byte("second\n"), 0o600)
+	encoded, _ = registryAuth(registries, "registry.example.com/bots/telegram:1.2")
+	if auth := decodeAuth(t, encoded); auth.Password != "second" {
+		t.Errorf("registryAuth() password = %v, want second", auth.Password)
+	}
+}
+
+func TestRegistryAuthDockerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	auth := base64.StdEncoding.EncodeToString([]byte("hub-user:hub-pass"))
+	os.WriteFile(path, []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"`+auth+`"}}}`), 0o600)
+	registries := []config.DockerRegistry{{Server: "docker.io", ConfigFile: path}}
+
+	encoded, err := registryAuth(registries, "acme/bot")
+	if err != nil {
+		t.Fatalf("registryAuth() error = %v", err)
+	}
+	if got := decodeAuth(t, encoded); got.Username != "hub-user" || got.Password != "hub-pass" {
+		t.Errorf("registryAuth() = %v/%v, want hub-user/hub-pass", got.Username, got.Password)
+	}
+}
+
+func TestRegistryAuthUnconfigured(t *testing.T) {
+	registries := []config.DockerRegistry{{Server: "registry.example.com", Username: "u", Password: "p"}}
+	encoded, err := registryAuth(registries, "alpine")
+	if err != nil || encoded != "" {
+		t.Errorf("registryAuth() = %q, %v, want empty", encoded, err)
+	}
+}
@@ -0,0 +1,288 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"io"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+var (
+	ErrUnknownHost        = errors.New("unknown docker host")
+	ErrContainerExited    = errors.New("container exited")
+	ErrContainerUnhealthy = errors.New("container is unhealthy")
+)
+
+const defaultHostName = "default"
+
+type dockerHost struct {
+	name   string
+	client *client.Client
+}
+
+type DockerRuntime struct {
+	hosts []dockerHost
+	cfg   *config.ExecutorConfig
+	mu    sync.Mutex
+	// secrets holds the tmpfs files of each container until it is removed
+	secrets map[string][]models.ContainerFile
+}
+
+func NewDockerRuntime(cfg *config.ExecutorConfig) *DockerRuntime {
+	hosts, err := newDockerHosts(cfg)
+	if err != nil {
+		panic(err)
+	}
+	return &DockerRuntime{hosts: hosts, cfg: cfg}
+}
+
+func newDockerHosts(cfg *config.ExecutorConfig) ([]dockerHost, error) {
+	if len(cfg.Docker.Hosts) == 0 {
+		cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+		if err != nil {
+			return nil, err
+		}
+		return []dockerHost{{name: defaultHostName, client: cli}}, nil
+	}
+	hosts := make([]dockerHost, 0, len(cfg.Docker.Hosts))
+	for _, h := range cfg.Docker.Hosts {
+		opts := []client.Opt{client.WithHost(h.Address), client.WithAPIVersionNegotiation()}
+		if h.TLSCACert != "" || h.TLSCert != "" {
+			opts = append(opts, client.WithTLSClientConfig(h.TLSCACert, h.TLSCert, h.TLSKey))
+		}
+		cli, err := client.NewClientWithOpts(opts...)
+		if err != nil {
+			return nil, fmt.Errorf("docker host %s: %w", h.Name, err)
+		}
+		hosts = append(hosts, dockerHost{name: h.Name, client: cli})
+	}
+	return hosts, nil
+}
+
+func (r *DockerRuntime) Name() string {
+	return "docker"
+}
+
+func (r *DockerRuntime) Hosts() []string {
+	names := make([]string, 0, len(r.hosts))
+	for _, h := range r.hosts {
+		names = append(names, h.name)
+	}
+	return names
+}
+
+// clientFor resolves the daemon a container lives on. Rows created before
+// multi-host support carry no host and belong to the first configured one.
+func (r *DockerRuntime) clientFor(host string) (*client.Client, error) {
+	if host == "" {
+		return r.hosts[0].client, nil
+	}
+	for _, h := range r.hosts {
+		if h.name == host {
+			return h.client, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownHost, host)
+}
+
+func (r *DockerRuntime) Create(ctx context.Context, bot models.Container, spec models.ContainerSpec) (*models.Container, error) {
+	cli, err := r.clientFor(bot.DockerHost)
+	if err != nil {
+		return nil, err
+	}
+	env := spec.Env
+	for _, f := range spec.Files {
+		if f.Env != "" {
+			env = append(env, f.Env+"="+f.Path)
+		}
+	}
+	config := &container.Config{
+		Image:  spec.Image,
+		Tty:    true,
+		Env:    env,
+		Labels: spec.Labels,
+	}
+	hostConfig := &container.HostConfig{
+		RestartPolicy: container.RestartPolicy{
+			Name: "unless-stopped",
+		},
+		NetworkMode: "host",
+		Resources: container.Resources{
+			NanoCPUs: int64(spec.CPUs * 1e9),
+			Memory:   spec.MemoryBytes,
+		},
+	}
+	secret, other := splitSecretFiles(r.cfg.Secrets.Dir, spec.Files)
+	if len(secret) > 0 {
+		image, _, err := cli.ImageInspectWithRaw(ctx, spec.Image)
+		if err != nil {
+			return nil, err
+		}
+		var entrypoint, cmd []string
+		if image.Config != nil {
+			entrypoint, cmd = image.Config.Entrypoint, image.Config.Cmd
+		}
+		config.Entrypoint, config.Cmd = waitForSecrets(r.cfg.Secrets.Dir, entrypoint, cmd)
+		config.Labels = map[string]string{secretFilesLabel: "true"}
+		maps.Copy(config.Labels, spec.Labels)
+		hostConfig.Tmpfs = map[string]string{r.cfg.Secrets.Dir: secretsTmpfsOpts}
+	}
+	resp, err := cli.ContainerCreate(ctx, config, hostConfig, nil, nil, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(other) > 0 {
+		if err := copyFiles(ctx, cli, resp.ID, other); err != nil {
+			cli.ContainerRemove(context.WithoutCancel(ctx), resp.ID, container.RemoveOptions{Force: true})
+			return nil, err
+		}
+	}
+	if len(secret) > 0 {
+		r.rememberSecrets(resp.ID, secret)
+	}
+	// bots share the host's network, so no port is published for them
+	bot.ContainerID = resp.ID
+	return &bot, nil
+}
+
+// copyFiles writes files into a created container through the daemon, which
+// also works for remote hosts where a bind mount would not. They live in the
+// container's writable layer and are deleted with it, so secrets go to a
+// tmpfs instead (see writeSecrets).
+func copyFiles(ctx context.Context, cli *client.Client, id string, files []models.ContainerFile) error {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    strings.TrimPrefix(f.Path, "/"),
+			Mode:    0o444,
+			Size:    int64(len(f.Content)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.Content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return cli.CopyToContainer(ctx, id, "/", &buf, container.CopyToContainerOptions{})
+}
+
+func (r *DockerRuntime) Start(ctx context.Context, bot models.Container) error {
+	cli, err := r.clientFor(bot.DockerHost)
+	if err != nil {
+		return err
+	}
+	secrets, held, err := r.secretsFor(ctx, cli, bot.ContainerID)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return fmt.Errorf("%w: %s", ErrSecretsLost, bot.ContainerID)
+	}
+	if err := cli.ContainerStart(ctx, bot.ContainerID, container.StartOptions{}); err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		return nil
+	}
+	return r.writeSecrets(ctx, cli, bot.ContainerID, secrets)
+}
+
+func (r *DockerRuntime) Stop(ctx context.Context, bot models.Container) error {
+	cli, err := r.clientFor(bot.DockerHost)
+	if err != nil {
+		return err
+	}
+	return cli.ContainerStop(ctx, bot.ContainerID, container.StopOptions{Timeout: &r.cfg.Docker.Timeout})
+}
+
+func (r *DockerRuntime) Remove(ctx context.Context, bot models.Container) error {
+	cli, err := r.clientFor(bot.DockerHost)
+	if err != nil {
+		return err
+	}
+	if err := cli.ContainerRemove(ctx, bot.ContainerID, container.RemoveOptions{Force: true}); err != nil {
+		return err
+	}
+	r.forgetSecrets(bot.ContainerID)
+	return nil
+}
+
+func (r *DockerRuntime) Logs(ctx context.Context, bot models.Container, w io.Writer) error {
+	cli, err := r.clientFor(bot.DockerHost)
+	if err != nil {
+		return err
+	}
+	out, err := cli.ContainerLogs(ctx, bot.ContainerID, container.LogsOptions{ShowStdout: true})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	// containers run with a tty, so the stream is not multiplexed
+	io.Copy(w, out)
+	return nil
+}
+
+// Healthy follows the image's HEALTHCHECK when it has one; otherwise a
+// running container counts as healthy.
+func (r *DockerRuntime) Healthy(ctx context.Context, bot models.Container) (bool, error) {
+	cli, err := r.clientFor(bot.DockerHost)
+	if err != nil {
+		return false, err
+	}
+	inspect, err := cli.ContainerInspect(ctx, bot.ContainerID)
+	if err != nil {
+		return false, err
+	}
+	state := inspect.State
+	if state == nil || state.Restarting {
+		return false, nil
+	}
+	if !state.Running {
+		return false, fmt.Errorf("%w: exit code %d", ErrContainerExited, state.ExitCode)
+	}
+	if state.Health == nil {
+		return true, nil
+	}
+	switch state.Health.Status {
+	case types.Healthy:
+		return true, nil
+	case types.Unhealthy:
+		return false, ErrContainerUnhealthy
+	}
+	return false, nil
+}
+
+func (r *DockerRuntime) Check(ctx context.Context) error {
+	var errs []error
+	for _, h := range r.hosts {
+		if _, err := h.client.Ping(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// IsNotFound also covers containers on hosts this executor does not have,
+// such as rows adopted from another executor, and containers whose secrets
+// it no longer holds, so they are recreated here.
+func (r *DockerRuntime) IsNotFound(err error) bool {
+	return client.IsErrNotFound(err) || errors.Is(err, ErrUnknownHost) || errors.Is(err, ErrSecretsLost)
+}
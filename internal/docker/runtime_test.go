@@ -0,0 +1,41 @@
+package docker
+
+import (
+	"context"
+	"executor/internal/core/models"
+	"testing"
+)
+
+func TestForeignHostIsNotFound(t *testing.T) {
+	r := &DockerRuntime{hosts: []dockerHost{{name: "local"}}}
+	err := r.Start(context.Background(), models.Container{ContainerID: "c1", DockerHost: "remote"})
+	if err == nil || !r.IsNotFound(err) {
+		t.Errorf("Start() on a foreign host error = %v, want not found", err)
+	}
+}
+
+func TestSplitSecretFiles(t *testing.T) {
+	files := []models.ContainerFile{
+		{Path: "/run/secrets/TOKEN"},
+		{Path: "/run/secrets-other/x"},
+		{Path: "/etc/bot.json"},
+	}
+	secret, other := splitSecretFiles("/run/secrets/", files)
+	if len(secret) != 1 || secret[0].Path != "/run/secrets/TOKEN" {
+		t.Errorf("splitSecretFiles() secret = %v, want [/run/secrets/TOKEN]", secret)
+	}
+	if len(other) != 2 {
+		t.Errorf("splitSecretFiles() other = %v, want 2 files", other)
+	}
+}
+
+func TestWaitForSecrets(t *testing.T) {
+	entrypoint, cmd := waitForSecrets("/run/it's", []string{"/bot"}, []string{"-v"})
+	want := `until [ -e '/run/it'\''s/.ready' ]; do sleep 0.2; done; exec "$@"`
+	if len(entrypoint) != 4 || entrypoint[2] != want {
+		t.Errorf("waitForSecrets() entrypoint = %q, want script %q", entrypoint, want)
+	}
+	if len(cmd) != 2 || cmd[0] != "/bot" || cmd[1] != "-v" {
+		t.Errorf("waitForSecrets() cmd = %q, want [/bot -v]", cmd)
+	}
+}
@@ -0,0 +1,138 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"executor/internal/core/models"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// ErrSecretsLost is returned by Start for a container whose secret files
+// this executor no longer holds, e.g. after a restart. It counts as not
+// found, so the bot is recreated with freshly rendered secrets.
+var ErrSecretsLost = errors.New("secret files of the container are gone")
+
+const (
+	// secretFilesLabel marks containers whose secrets live on a tmpfs
+	secretFilesLabel = "executor/secret-files"
+	secretsReadyFile = ".ready"
+	secretsTmpfsOpts = "mode=0755,noexec,nosuid,size=1m"
+)
+
+// splitSecretFiles separates the files that go to the tmpfs at dir from
+// the ones copied into the container before start.
+func splitSecretFiles(dir string, files []models.ContainerFile) (secret, other []models.ContainerFile) {
+	for _, f := range files {
+		if dir != "" && strings.HasPrefix(f.Path, strings.TrimSuffix(dir, "/")+"/") {
+			secret = append(secret, f)
+		} else {
+			other = append(other, f)
+		}
+	}
+	return secret, other
+}
+
+// waitForSecrets wraps the image's entrypoint so the bot only starts once
+// Start has written its secrets to the tmpfs, which is empty on every start.
+func waitForSecrets(dir string, entrypoint, cmd []string) ([]string, []string) {
+	script := fmt.Sprintf(`until [ -e %s ]; do sleep 0.2; done; exec "$@"`, shellQuote(path.Join(dir, secretsReadyFile)))
+	return []string{"/bin/sh", "-c", script, "executor-wait"}, append(slices.Clone(entrypoint), cmd...)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (r *DockerRuntime) rememberSecrets(id string, files []models.ContainerFile) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.secrets == nil {
+		r.secrets = make(map[string][]models.ContainerFile)
+	}
+	r.secrets[id] = files
+}
+
+func (r *DockerRuntime) forgetSecrets(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.secrets, id)
+}
+
+// secretsFor returns the files to write after start; ok is false when the
+// container has secrets this executor does not hold.
+func (r *DockerRuntime) secretsFor(ctx context.Context, cli *client.Client, id string) ([]models.ContainerFile, bool, error) {
+	r.mu.Lock()
+	files, held := r.secrets[id]
+	r.mu.Unlock()
+	if held {
+		return files, true, nil
+	}
+	inspect, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	if inspect.Config != nil && inspect.Config.Labels[secretFilesLabel] != "" {
+		return nil, false, nil
+	}
+	return nil, true, nil
+}
+
+// writeSecrets fills the container's tmpfs through exec, since the daemon
+// cannot copy into a tmpfs mount, and then releases the wrapped entrypoint.
+func (r *DockerRuntime) writeSecrets(ctx context.Context, cli *client.Client, id string, files []models.ContainerFile) error {
+	for _, f := range files {
+		if err := execWrite(ctx, cli, id, f.Path, f.Content); err != nil {
+			return fmt.Errorf("writing %s: %w", f.Path, err)
+		}
+	}
+	return execWrite(ctx, cli, id, path.Join(r.cfg.Secrets.Dir, secretsReadyFile), nil)
+}
+
+func execWrite(ctx context.Context, cli *client.Client, id, file string, content []byte) error {
+	exec, err := cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		User:        "0",
+		AttachStdin: true,
+		Cmd:         []string{"/bin/sh", "-c", `umask 0222 && cat > "$1"`, "executor-write", file},
+	})
+	if err != nil {
+		return err
+	}
+	conn, err := cli.ContainerExecAttach(ctx, exec.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := io.Copy(conn.Conn, bytes.NewReader(content)); err != nil {
+		return err
+	}
+	if err := conn.CloseWrite(); err != nil {
+		return err
+	}
+	io.Copy(io.Discard, conn.Reader)
+	for {
+		inspect, err := cli.ContainerExecInspect(ctx, exec.ID)
+		if err != nil {
+			return err
+		}
+		if !inspect.Running {
+			if inspect.ExitCode != 0 {
+				return fmt.Errorf("exit code %d", inspect.ExitCode)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
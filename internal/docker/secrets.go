@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"executor/internal/core/models"
+	"path"
+	"slices"
+	"strings"
+)
+
+// moveSecretsToFiles takes the secret variables out of the spec env and
+// delivers them as files; the bot reads NAME_FILE instead of NAME. Runtimes
+// remove the files together with the container.
+func (d *DockerService) moveSecretsToFiles(spec *models.ContainerSpec, overrides []models.EnvOverride) {
+	if d.cfg.Secrets.Delivery != "file" {
+		return
+	}
+	secret := func(key string) bool {
+		if slices.Contains(d.cfg.Secrets.Env, key) {
+			return true
+		}
+		return slices.ContainsFunc(overrides, func(o models.EnvOverride) bool {
+			return o.Secret && o.Key == key
+		})
+	}
+	env := spec.Env[:0]
+	for _, kv := range spec.Env {
+		key, value, _ := strings.Cut(kv, "=")
+		if !secret(key) {
+			env = append(env, kv)
+			continue
+		}
+		spec.Files = append(spec.Files, models.ContainerFile{
+			Path:    path.Join(d.cfg.Secrets.Dir, key),
+			Env:     key + "_FILE",
+			Content: []byte(value),
+		})
+	}
+	spec.Env = env
+}
@@ -0,0 +1,35 @@
+package docker
+
+import (
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"reflect"
+	"testing"
+)
+
+func TestMoveSecretsToFiles(t *testing.T) {
+	d := &DockerService{cfg: &config.ExecutorConfig{Secrets: config.Secrets{
+		Delivery: "file",
+		Dir:      "/run/secrets",
+		Env:      config.DefaultSecretEnv,
+	}}}
+	spec := &models.ContainerSpec{Env: []string{"POSTGRES_HOST=db", "POSTGRES_PASSWORD=pw", "CRM_KEY=k"}}
+	d.moveSecretsToFiles(spec, []models.EnvOverride{{Key: "CRM_KEY", Value: "k", Secret: true}})
+
+	if want := []string{"POSTGRES_HOST=db"}; !reflect.DeepEqual(spec.Env, want) {
+		t.Errorf("moveSecretsToFiles() env = %v, want %v", spec.Env, want)
+	}
+	want := []models.ContainerFile{
+		{Path: "/run/secrets/POSTGRES_PASSWORD", Env: "POSTGRES_PASSWORD_FILE", Content: []byte("pw")},
+		{Path: "/run/secrets/CRM_KEY", Env: "CRM_KEY_FILE", Content: []byte("k")},
+	}
+	if !reflect.DeepEqual(spec.Files, want) {
+		t.Errorf("moveSecretsToFiles() files = %+v, want %+v", spec.Files, want)
+	}
+
+	d.cfg.Secrets.Delivery = "env"
+	spec = &models.ContainerSpec{Env: []string{"POSTGRES_PASSWORD=pw"}}
+	if d.moveSecretsToFiles(spec, nil); len(spec.Files) != 0 || len(spec.Env) != 1 {
+		t.Errorf("moveSecretsToFiles() with env delivery = %v, %v, want unchanged", spec.Env, spec.Files)
+	}
+}
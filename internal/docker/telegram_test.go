@@ -0,0 +1,43 @@
+package docker
+
+import (
+	"encoding/json"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"strings"
+	"testing"
+)
+
+func TestAddTelegramSettings(t *testing.T) {
+	cfg := &config.ExecutorConfig{Telegram: config.Telegram{
+		ErrorMessage: "oops",
+		Delivery:     "env",
+		EnvName:      "TELEGRAM_SETTINGS",
+		FilePath:     "/etc/bot/telegram.json",
+	}}
+	d := &DockerService{cfg: cfg}
+
+	spec := &models.ContainerSpec{}
+	if err := d.addTelegramSettings(spec, &models.TelegramSettings{InformationURL: "https://example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Env) != 1 || !strings.HasPrefix(spec.Env[0], "TELEGRAM_SETTINGS=") {
+		t.Fatalf("addTelegramSettings() env = %v, want TELEGRAM_SETTINGS", spec.Env)
+	}
+	var got models.TelegramSettings
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(spec.Env[0], "TELEGRAM_SETTINGS=")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ErrorMessage != "oops" || got.InformationURL != "https://example.com" {
+		t.Errorf("addTelegramSettings() settings = %+v, want merged", got)
+	}
+
+	cfg.Telegram.Delivery = "file"
+	spec = &models.ContainerSpec{}
+	if err := d.addTelegramSettings(spec, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.Env) != 0 || len(spec.Files) != 1 || spec.Files[0].Env != "TELEGRAM_SETTINGS_FILE" || spec.Files[0].Path != "/etc/bot/telegram.json" {
+		t.Errorf("addTelegramSettings() = %v, %+v, want one file", spec.Env, spec.Files)
+	}
+}
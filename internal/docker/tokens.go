@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/core/ports"
+	"fmt"
+)
+
+var ErrNoActiveKey = errors.New("token_encryption.key_id is not set")
+
+// ReencryptTokens moves every bot's api token onto the active key, including
+// tokens still stored in plaintext. It returns how many rows were rewritten;
+// once it reports none for a retired key, that key can leave the config.
+func (d *DockerService) ReencryptTokens(ctx context.Context) (int, error) {
+	active := d.tokens.ActiveKeyID()
+	if active == "" {
+		return 0, ErrNoActiveKey
+	}
+	bots, err := d.repo.GetAllBots(ctx)
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return 0, err
+	}
+	var (
+		updated int
+		errs    []error
+	)
+	for _, c := range bots {
+		if c.ApiTokenKeyID == active {
+			continue
+		}
+		ok, err := d.reencryptLocked(ctx, c.Id, c.BotID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("[bot_id: %d] %w", c.BotID, err))
+			continue
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated, errors.Join(errs...)
+}
+
+func (d *DockerService) reencryptLocked(ctx context.Context, id, botID int64) (bool, error) {
+	unlock, err := d.repo.LockBot(ctx, botID)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	// a rollout may have replaced the row since it was listed
+	current, err := d.repo.GetContainerById(ctx, id)
+	if errors.Is(err, ports.ErrBotNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if current.ApiTokenKeyID == d.tokens.ActiveKeyID() {
+		return false, nil
+	}
+	token, keyID, err := d.tokens.Reencrypt(current.ApiToken, current.ApiTokenKeyID)
+	if err != nil {
+		return false, err
+	}
+	return true, d.repo.SetBotToken(ctx, current.Id, token, keyID)
+}
@@ -0,0 +1,29 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/tokencrypt"
+	"testing"
+)
+
+func TestRunRejectsUndecryptableToken(t *testing.T) {
+	// the bot already has a container, so nothing else would decrypt the token
+	repo := &fakeRepo{bots: []dto.ContainerDbo{{Id: 1, BotID: 1, State: "stopped", ExecutorID: "a", Image: "bot:1"}}}
+	runtime := &fakeRuntime{}
+	d := newRolloutService(t, repo, runtime)
+	d.tokens, _ = tokencrypt.New(config.TokenEncryption{})
+	err := d.DockerFactory(context.Background(), models.BotMessage{
+		Type:    string(models.RUN),
+		Payload: models.BotPayload{BotID: 1, ApiToken: "wrapped.sealed", ApiTokenKeyID: "retired"},
+	})
+	if !errors.Is(err, tokencrypt.ErrUnknownKey) {
+		t.Errorf("DockerFactory() error = %v, want %v", err, tokencrypt.ErrUnknownKey)
+	}
+	if repo.bots[0].State != "stopped" || runtime.created != 0 {
+		t.Errorf("DockerFactory() started the bot with a token it cannot decrypt")
+	}
+}
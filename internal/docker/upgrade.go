@@ -0,0 +1,343 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/distribution/reference"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrUpgradeHalted = errors.New("upgrade halted after too many failures")
+	ErrHealthTimeout = errors.New("timed out waiting for container to become healthy")
+)
+
+type upgradeTask struct {
+	bot   dto.ContainerDbo
+	image string
+	// previous is recorded as the replacement's previous image
+	previous string
+	// variant replaces the bot's recorded variant when set
+	variant string
+}
+
+type upgradeOptions struct {
+	batchSize   int
+	concurrency int
+	maxFailures int
+}
+
+// upgradeOptions lets a payload lower the configured batch size and
+// concurrency but not raise them: max_open_conns is validated against
+// upgrade.concurrency, and every bot replaced at once holds a lock connection.
+func (d *DockerService) upgradeOptions(payload *models.UpgradePayload) upgradeOptions {
+	opts := upgradeOptions{
+		batchSize:   d.cfg.Upgrade.BatchSize,
+		concurrency: d.cfg.Upgrade.Concurrency,
+		maxFailures: d.cfg.Upgrade.MaxFailures,
+	}
+	if payload == nil {
+		return opts
+	}
+	if payload.BatchSize > 0 {
+		opts.batchSize = min(payload.BatchSize, opts.batchSize)
+	}
+	if payload.Concurrency > 0 {
+		opts.concurrency = min(payload.Concurrency, opts.concurrency)
+	}
+	if payload.MaxFailures != nil && *payload.MaxFailures >= 0 {
+		opts.maxFailures = *payload.MaxFailures
+	}
+	return opts
+}
+
+// UpgradeContainers recreates every running bot owned by this executor on
+// the target image of its kind. The current image is kept as the bot's
+// previous image so RollbackContainers can return to it.
+func (d *DockerService) UpgradeContainers(ctx context.Context, payload *models.UpgradePayload) error {
+	explicit, only := "", ""
+	if payload != nil && payload.Image != "" {
+		explicit = payload.Image
+		only, _, _ = d.botKind(payload.Kind)
+		if only == "" {
+			return fmt.Errorf("%w: %s", ErrUnknownKind, payload.Kind)
+		}
+	}
+	bots, err := d.runningBots(ctx)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]string)
+	tasks := make([]upgradeTask, 0, len(bots))
+	for _, c := range bots {
+		kind, botKind, err := d.botKind(c.Kind)
+		if err != nil {
+			fmt.Printf("[upgrade] bot_id: %d skipped: %s\n", c.BotID, err.Error())
+			continue
+		}
+		if only != "" && kind != only {
+			continue
+		}
+		target, ok := targets[kind]
+		if !ok {
+			image := botKind.Image
+			if explicit != "" {
+				image = explicit
+			}
+			if target, err = d.resolveTarget(ctx, image); err != nil {
+				return err
+			}
+			targets[kind] = target
+			fmt.Printf("[upgrade] upgrading %s bots to %s\n", kind, target)
+		}
+		if c.Image == target {
+			continue
+		}
+		tasks = append(tasks, upgradeTask{bot: c, image: target, previous: c.Image, variant: models.VariantStable})
+	}
+	return d.rollout(ctx, "upgrade", tasks, d.upgradeOptions(payload))
+}
+
+// RollbackContainers returns every running bot owned by this executor to its
+// previously recorded image.
+func (d *DockerService) RollbackContainers(ctx context.Context, payload *models.UpgradePayload) error {
+	bots, err := d.runningBots(ctx)
+	if err != nil {
+		return err
+	}
+	tasks := make([]upgradeTask, 0, len(bots))
+	for _, c := range bots {
+		if c.PreviousImage == "" || c.PreviousImage == c.Image {
+			continue
+		}
+		tasks = append(tasks, upgradeTask{bot: c, image: c.PreviousImage, previous: c.Image})
+	}
+	return d.rollout(ctx, "rollback", tasks, d.upgradeOptions(payload))
+}
+
+// resolveTarget pulls a tag once so the whole rollout pins the same digest.
+func (d *DockerService) resolveTarget(ctx context.Context, image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := named.(reference.Canonical); ok {
+		return image, nil
+	}
+	host := d.runtime.Hosts()[0]
+	ctx, cancel := withTimeout(ctx, d.cfg.Docker.PullTimeout)
+	defer cancel()
+	if puller, ok := d.runtime.(ports.ImagePuller); ok && d.cfg.Docker.PullPolicy != PullNever {
+		if err := puller.PullImage(ctx, host, image); err != nil {
+			return "", err
+		}
+	}
+	if resolver, ok := d.runtime.(ports.ImageResolver); ok {
+		return resolver.ResolveImage(ctx, host, image)
+	}
+	return image, nil
+}
+
+func (d *DockerService) runningBots(ctx context.Context) ([]dto.ContainerDbo, error) {
+	containers, err := d.repo.GetBotsByExecutor(ctx, d.owner.ExecutorID())
+	if err != nil && !errors.Is(err, ports.ErrBotsNotFound) {
+		return nil, err
+	}
+	running := make([]dto.ContainerDbo, 0, len(containers))
+	for _, c := range containers {
+		if c.State == "running" {
+			running = append(running, c)
+		}
+	}
+	return running, nil
+}
+
+func (d *DockerService) rollout(ctx context.Context, op string, tasks []upgradeTask, opts upgradeOptions) error {
+	if len(tasks) == 0 {
+		fmt.Printf("[%s] nothing to do\n", op)
+		return nil
+	}
+	var (
+		mu       sync.Mutex
+		errs     []error
+		replaced int
+	)
+	batches := (len(tasks) + opts.batchSize - 1) / opts.batchSize
+	for i := 0; i < batches; i++ {
+		batch := tasks[i*opts.batchSize : min((i+1)*opts.batchSize, len(tasks))]
+		g, gctx := errgroup.WithContext(ctx)
+		g.SetLimit(opts.concurrency)
+		for _, task := range batch {
+			g.Go(func() error {
+				if err := d.replaceLocked(gctx, task); err != nil {
+					mu.Lock()
+					errs = append(errs, fmt.Errorf("[bot_id: %d] %w", task.bot.BotID, err))
+					mu.Unlock()
+					fmt.Printf("[%s] bot_id: %d failed, kept on %s: %s\n", op, task.bot.BotID, task.bot.Image, err.Error())
+					return nil
+				}
+				mu.Lock()
+				replaced++
+				mu.Unlock()
+				fmt.Printf("[%s] bot_id: %d now on %s\n", op, task.bot.BotID, task.image)
+				return nil
+			})
+		}
+		g.Wait()
+		fmt.Printf("[%s] batch %d of %d done: %d replaced, %d failed\n", op, i+1, batches, replaced, len(errs))
+		if len(errs) > opts.maxFailures {
+			return fmt.Errorf("%w: %s: %d of %d bots failed: %w", ErrUpgradeHalted, op, len(errs), len(tasks), errors.Join(errs...))
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s: %d of %d bots failed: %w", op, len(errs), len(tasks), errors.Join(errs...))
+	}
+	fmt.Printf("[%s] %d bots replaced\n", op, replaced)
+	return nil
+}
+
+func (d *DockerService) replaceLocked(ctx context.Context, task upgradeTask) error {
+	unlock, err := d.repo.LockBot(ctx, task.bot.BotID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	// the row may have changed while the bot waited for its batch
+	current, err := d.repo.GetContainerById(ctx, task.bot.Id)
+	if err != nil {
+		return err
+	}
+	if current.State != "running" || current.Image != task.bot.Image {
+		fmt.Printf("[bot_id: %d] changed since the rollout started, skipping\n", current.BotID)
+		return nil
+	}
+	old := current.ToValue()
+	variant := old.Variant
+	if task.variant != "" {
+		variant = task.variant
+	}
+	err = d.replace(ctx, old, task.image, task.previous, variant)
+	if err == nil {
+		return nil
+	}
+	// the old row is back; put its container back on the image it was running
+	if rerr := d.replace(context.WithoutCancel(ctx), old, old.Image, old.PreviousImage, old.Variant); rerr != nil {
+		return errors.Join(err, fmt.Errorf("could not restore %s, the bot is restarted on its next run: %w", old.Image, rerr))
+	}
+	return err
+}
+
+// replace swaps a bot's container for one on image. The old container goes
+// first: a Telegram token cannot be polled by two containers at once. The
+// old row is only retired until the new container is healthy, so a failed
+// replacement leaves the bot's row in place and discards the new one.
+func (d *DockerService) replace(ctx context.Context, old models.Container, image, previous, variant string) error {
+	if err := d.removeContainer(ctx, old); err != nil {
+		return err
+	}
+	if err := d.repo.RetireBotById(ctx, old.Id); err != nil {
+		return err
+	}
+	bot, err := d.startReplacement(ctx, old, image, previous, variant)
+	if err != nil {
+		if bot != nil {
+			if rerr := d.discard(context.WithoutCancel(ctx), *bot); rerr != nil {
+				err = errors.Join(err, rerr)
+			}
+		}
+		if rerr := d.repo.RestoreBotById(context.WithoutCancel(ctx), old.Id); rerr != nil {
+			err = errors.Join(err, rerr)
+		}
+		return err
+	}
+	if err := d.repo.DeleteBotById(ctx, old.Id); err != nil {
+		fmt.Printf("[bot_id: %d] could not delete replaced row %d: %s\n", old.BotID, old.Id, err.Error())
+	}
+	return nil
+}
+
+// startReplacement returns the new container even when it fails to become
+// healthy so the caller can discard it.
+func (d *DockerService) startReplacement(ctx context.Context, old models.Container, image, previous, variant string) (*models.Container, error) {
+	model := old
+	model.Id = 0
+	model.ContainerID = ""
+	model.Port = 0
+	_, kind, err := d.botKind(old.Kind)
+	if err != nil {
+		return nil, err
+	}
+	model.ContainerName = d.containerName(kind.NamePrefix, old.Name)
+	model.Image = image
+	model.PreviousImage = previous
+	model.Variant = variant
+	model.State = "created"
+	model.ExecutorID = d.owner.ExecutorID()
+	bot, err := d.CreateContainer(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.RunContainer(ctx, *bot); err != nil {
+		return bot, err
+	}
+	return bot, d.waitHealthy(ctx, *bot)
+}
+
+func (d *DockerService) removeContainer(ctx context.Context, bot models.Container) error {
+	if err := d.stopContainer(ctx, bot); err != nil && !d.runtime.IsNotFound(err) {
+		return err
+	}
+	if err := d.runtime.Remove(ctx, bot); err != nil && !d.runtime.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (d *DockerService) discard(ctx context.Context, bot models.Container) error {
+	if err := d.removeContainer(ctx, bot); err != nil {
+		return err
+	}
+	return d.repo.DeleteBotById(ctx, bot.Id)
+}
+
+func (d *DockerService) waitHealthy(ctx context.Context, bot models.Container) error {
+	reporter, ok := d.runtime.(ports.HealthReporter)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := withTimeout(ctx, d.cfg.Upgrade.HealthTimeout)
+	defer cancel()
+	ticker := time.NewTicker(d.cfg.Upgrade.HealthInterval)
+	defer ticker.Stop()
+	var healthySince time.Time
+	for {
+		healthy, err := reporter.Healthy(ctx, bot)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		switch {
+		case !healthy:
+			healthySince = time.Time{}
+		case healthySince.IsZero():
+			healthySince = time.Now()
+		}
+		if !healthySince.IsZero() && time.Since(healthySince) >= d.cfg.Upgrade.HealthGrace {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %s", ErrHealthTimeout, bot.ContainerID)
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"executor/internal/envtemplate"
+	"testing"
+)
+
+func TestUpgradeOptions(t *testing.T) {
+	d := &DockerService{cfg: &config.ExecutorConfig{Upgrade: config.Upgrade{BatchSize: 5, Concurrency: 2, MaxFailures: 1}}}
+
+	if got := d.upgradeOptions(nil); got != (upgradeOptions{batchSize: 5, concurrency: 2, maxFailures: 1}) {
+		t.Errorf("upgradeOptions(nil) = %+v, want config defaults", got)
+	}
+	zero := 0
+	got := d.upgradeOptions(&models.UpgradePayload{Concurrency: 1, MaxFailures: &zero})
+	if got != (upgradeOptions{batchSize: 5, concurrency: 1, maxFailures: 0}) {
+		t.Errorf("upgradeOptions() = %+v, want batch 5, concurrency 1, max failures 0", got)
+	}
+	// payloads cannot raise the limits the pool was sized for
+	got = d.upgradeOptions(&models.UpgradePayload{BatchSize: 100, Concurrency: 64})
+	if got != (upgradeOptions{batchSize: 5, concurrency: 2, maxFailures: 1}) {
+		t.Errorf("upgradeOptions() = %+v, want batch 5, concurrency 2", got)
+	}
+}
+
+func newRolloutService(t *testing.T, repo *fakeRepo, runtime *fakeRuntime) *DockerService {
+	cfg := &config.ExecutorConfig{
+		BotKinds: map[string]config.BotKind{config.DefaultBotKind: {Image: "bot:2"}},
+		Upgrade:  config.Upgrade{BatchSize: 1, Concurrency: 1},
+		Shutdown: config.Shutdown{StopConcurrency: 1},
+	}
+	env, err := envtemplate.New(nil, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &DockerService{
+		runtime: runtime,
+		repo:    repo,
+		owner:   &fakeOwner{id: "a"},
+		env:     map[string]*envtemplate.EnvTemplate{config.DefaultBotKind: env},
+		cfg:     cfg,
+	}
+}
+
+func TestUpgradeHaltsAfterMaxFailures(t *testing.T) {
+	repo := &fakeRepo{bots: []dto.ContainerDbo{
+		{Id: 1, BotID: 1, State: "running", ExecutorID: "a", Image: "bot:1"},
+		{Id: 2, BotID: 2, State: "running", ExecutorID: "a", Image: "bot:1"},
+		{Id: 3, BotID: 3, State: "running", ExecutorID: "a", Image: "bot:1"},
+	}}
+	d := newRolloutService(t, repo, &fakeRuntime{broken: map[string]bool{"bot:2": true}})
+	zero := 0
+	err := d.UpgradeContainers(context.Background(), &models.UpgradePayload{Image: "bot:2", MaxFailures: &zero})
+	if !errors.Is(err, ErrUpgradeHalted) {
+		t.Fatalf("UpgradeContainers() error = %v, want %v", err, ErrUpgradeHalted)
+	}
+	for _, id := range []int64{1, 2, 3} {
+		rows := repo.live(id)
+		if len(rows) != 1 || rows[0].Image != "bot:1" || rows[0].State != "running" {
+			t.Errorf("bot_id %d rows = %+v, want one running row on bot:1", id, rows)
+		}
+	}
+	if rows := repo.live(2); len(rows) == 1 && rows[0].Id != 2 {
+		t.Errorf("bot_id 2 was replaced after the rollout halted")
+	}
+}
+
+func TestRollbackContainers(t *testing.T) {
+	repo := &fakeRepo{bots: []dto.ContainerDbo{
+		{Id: 1, BotID: 1, State: "running", ExecutorID: "a", Image: "bot:2", PreviousImage: "bot:1"},
+		{Id: 2, BotID: 2, State: "running", ExecutorID: "a", Image: "bot:2"},
+	}}
+	d := newRolloutService(t, repo, &fakeRuntime{})
+	if err := d.RollbackContainers(context.Background(), nil); err != nil {
+		t.Fatalf("RollbackContainers() error = %v", err)
+	}
+	rows := repo.live(1)
+	if len(rows) != 1 || rows[0].Image != "bot:1" || rows[0].PreviousImage != "bot:2" {
+		t.Errorf("bot_id 1 rows = %+v, want one row on bot:1 with previous bot:2", rows)
+	}
+	if rows := repo.live(2); len(rows) != 1 || rows[0].Id != 2 {
+		t.Errorf("bot_id 2 rows = %+v, want the row without a previous image untouched", rows)
+	}
+}
+
+func TestReplaceKeepsRowWhenRestoreFails(t *testing.T) {
+	repo := &fakeRepo{bots: []dto.ContainerDbo{
+		{Id: 1, BotID: 1, State: "running", ExecutorID: "a", Image: "bot:1"},
+	}}
+	d := newRolloutService(t, repo, &fakeRuntime{broken: map[string]bool{"bot:1": true, "bot:2": true}})
+	err := d.replaceLocked(context.Background(), upgradeTask{bot: repo.bots[0], image: "bot:2", previous: "bot:1"})
+	if !errors.Is(err, errFakeStart) {
+		t.Fatalf("replaceLocked() error = %v, want %v", err, errFakeStart)
+	}
+	if len(repo.bots) != 1 || repo.bots[0].Id != 1 || repo.bots[0].DeletedAt.Valid {
+		t.Errorf("rows = %+v, want only the original row, not retired", repo.bots)
+	}
+}
@@ -0,0 +1,112 @@
+package envtemplate
+
+import (
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var ErrInvalidEntry = errors.New("invalid env template entry")
+
+var validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Default is the environment every bot container received before the list
+// became configurable.
+var Default = []string{
+	"POSTGRES_HOST={{.Config.Postgres.Host}}",
+	"POSTGRES_PORT={{.Config.Postgres.Port}}",
+	"POSTGRES_USER={{.Config.Postgres.User}}",
+	"POSTGRES_PASSWORD={{.Config.Postgres.Password}}",
+	"POSTGRES_DB_NAME={{.Config.Postgres.DBName}}",
+	"POSTGRES_SSL_MODE={{.Config.Postgres.SSLMode}}",
+	"POSTGRES_MIGRATIONS_PATH={{.Config.Postgres.MigrationsPath}}",
+	"MINIO_HOST={{.Config.MiniO.Host}}",
+	"MINIO_PORT={{.Config.MiniO.Port}}",
+	"MINIO_ROOT_USER={{.Config.MiniO.User}}",
+	"MINIO_ROOT_PASSWORD={{.Config.MiniO.Password}}",
+	"MINIO_ARTICLES_BUCKET={{.Config.MiniO.BucketArticles}}",
+	"MINIO_ATTACHMENTS_BUCKET={{.Config.MiniO.BucketAttachments}}",
+	"MINIO_AVATARS_BUCKET={{.Config.MiniO.BucketAvatars}}",
+	"MINIO_USE_SSL={{.Config.MiniO.UseSsl}}",
+	"MINIO_URL_LIFETIME={{.Config.MiniO.UrlLifetime}}",
+	"TELEGRAM_BOT_TOKEN={{.Bot.ApiToken}}",
+	"SEARCH_URL={{.Config.SearchUrl}}",
+	"CONTAINER_BOT_ID={{.Bot.BotID}}",
+	"CONTAINER_PROJECT_ID={{.Bot.ProjectID}}",
+	"CONTAINER_USER_ID={{.Bot.UserID}}",
+	"CONTAINER_NAME={{.Bot.Name}}",
+	"CONTAINER_DESCRIPTION={{.Bot.Description}}",
+	"CONTAINER_ICON={{.Bot.Icon}}",
+	"OPEN_ROUTER_API_TOKEN={{.Config.OpenRouterAi.Token}}",
+	"OPEN_ROUTER_API_MODEL={{.Config.OpenRouterAi.Model}}",
+	"OPEN_ROUTER_API_URL={{.Config.OpenRouterAi.URL}}",
+	"GIGACHAT_GRPC_ADDRESS={{.Config.GigaChatAi.GRPCAddress}}",
+	"GIGACHAT_AUTH_URL={{.Config.GigaChatAi.AuthURL}}",
+	"GIGACHAT_AUTHORIZATION_KEY={{.Config.GigaChatAi.AuthorizationKey}}",
+	"GIGACHAT_SCOPE={{.Config.GigaChatAi.Scope}}",
+	"GIGACHAT_MODEL={{.Config.GigaChatAi.Model}}",
+}
+
+// Data is what an entry is rendered against.
+type Data struct {
+	Bot    models.Container
+	Config *config.ExecutorConfig
+}
+
+type variable struct {
+	name string
+	tmpl *template.Template
+}
+
+type EnvTemplate struct {
+	vars []variable
+	cfg  *config.ExecutorConfig
+}
+
+// New parses entries of the form NAME=template and renders each once against
+// an empty bot, so unknown fields fail at startup rather than on the first
+// container. An empty list falls back to Default.
+func New(entries []string, cfg *config.ExecutorConfig) (*EnvTemplate, error) {
+	if len(entries) == 0 {
+		entries = Default
+	}
+	t := &EnvTemplate{vars: make([]variable, 0, len(entries)), cfg: cfg}
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		name, text, ok := strings.Cut(entry, "=")
+		if !ok || !validName.MatchString(name) {
+			return nil, fmt.Errorf("%w: [%d] %q: expected NAME=template", ErrInvalidEntry, i, entry)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: [%d] duplicate variable %s", ErrInvalidEntry, i, name)
+		}
+		seen[name] = true
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: [%d] %w", ErrInvalidEntry, i, err)
+		}
+		t.vars = append(t.vars, variable{name: name, tmpl: tmpl})
+	}
+	if _, err := t.Render(models.Container{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *EnvTemplate) Render(bot models.Container) ([]string, error) {
+	data := Data{Bot: bot, Config: t.cfg}
+	env := make([]string, 0, len(t.vars))
+	var b strings.Builder
+	for _, v := range t.vars {
+		b.Reset()
+		if err := v.tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidEntry, err)
+		}
+		env = append(env, v.name+"="+b.String())
+	}
+	return env, nil
+}
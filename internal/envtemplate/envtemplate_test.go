@@ -0,0 +1,61 @@
+package envtemplate
+
+import (
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// the default must render exactly what the hard-coded list did, or every
+// existing container would show up as drifted
+func TestDefaultMatchesLegacyEnv(t *testing.T) {
+	cfg := &config.ExecutorConfig{
+		Postgres:     config.Postgres{Host: "db", Port: 5432, User: "u", Password: "p", DBName: "bots", SSLMode: "disable", MigrationsPath: "/m"},
+		MiniO:        config.MiniO{Host: "s3", Port: 9000, UseSsl: true, UrlLifetime: 4 * time.Hour},
+		SearchUrl:    "http://search",
+		OpenRouterAi: config.OpenRouterAi{Model: "gpt"},
+	}
+	bot := models.Container{BotID: 1, ProjectID: 2, UserID: 3, Name: "Бот", ApiToken: "t"}
+	tmpl, err := New(nil, cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	got, err := tmpl.Render(bot)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := []string{
+		fmt.Sprintf("POSTGRES_PORT=%d", cfg.Postgres.Port),
+		fmt.Sprintf("MINIO_USE_SSL=%t", cfg.MiniO.UseSsl),
+		fmt.Sprintf("MINIO_URL_LIFETIME=%s", cfg.MiniO.UrlLifetime),
+		fmt.Sprintf("CONTAINER_BOT_ID=%d", bot.BotID),
+		fmt.Sprintf("CONTAINER_NAME=%s", bot.Name),
+		fmt.Sprintf("OPEN_ROUTER_API_MODEL=%s", cfg.OpenRouterAi.Model),
+	}
+	for _, kv := range want {
+		if !slices.Contains(got, kv) {
+			t.Errorf("Render() is missing %q", kv)
+		}
+	}
+	if len(got) != len(Default) {
+		t.Errorf("Render() = %d variables, want %d", len(got), len(Default))
+	}
+}
+
+func TestNewRejectsInvalidEntries(t *testing.T) {
+	for _, entries := range [][]string{
+		{"NO_EQUALS"},
+		{"1BAD={{.Bot.BotID}}"},
+		{"A=1", "A=2"},
+		{"A={{.Bot.Missing}}"},
+		{"A={{.Config.Postgres.Host"},
+	} {
+		if _, err := New(entries, &config.ExecutorConfig{}); !errors.Is(err, ErrInvalidEntry) {
+			t.Errorf("New(%q) error = %v, want %v", entries, err, ErrInvalidEntry)
+		}
+	}
+}
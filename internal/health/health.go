@@ -0,0 +1,140 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"executor/internal/core/config"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOk   = "ok"
+	StatusFail = "fail"
+)
+
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+type HealthService struct {
+	mu        sync.RWMutex
+	liveness  []check
+	readiness []check
+	timeout   time.Duration
+	server    *http.Server
+}
+
+func NewHealthService(cfg *config.ExecutorConfig) *HealthService {
+	h := &HealthService{timeout: cfg.Http.CheckTimeout}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", h.handleLiveness)
+	mux.HandleFunc("/readyz", h.handleReadiness)
+	h.server = &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.Http.Host, cfg.Http.Port),
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return h
+}
+
+func (h *HealthService) AddLivenessCheck(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.liveness = append(h.liveness, check{name: name, fn: fn})
+}
+
+func (h *HealthService) AddReadinessCheck(name string, fn CheckFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = append(h.readiness, check{name: name, fn: fn})
+}
+
+func (h *HealthService) Liveness(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]check(nil), h.liveness...)
+	h.mu.RUnlock()
+	return h.run(ctx, checks)
+}
+
+func (h *HealthService) Readiness(ctx context.Context) Report {
+	h.mu.RLock()
+	checks := append([]check(nil), h.readiness...)
+	h.mu.RUnlock()
+	return h.run(ctx, checks)
+}
+
+func (h *HealthService) run(ctx context.Context, checks []check) Report {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	report := Report{Status: StatusOk, Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			if err := c.fn(ctx); err != nil {
+				results[i] = CheckResult{Status: StatusFail, Error: err.Error()}
+				return
+			}
+			results[i] = CheckResult{Status: StatusOk}
+		}(i, c)
+	}
+	wg.Wait()
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOk {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (h *HealthService) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.Liveness(r.Context()))
+}
+
+func (h *HealthService) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.Readiness(r.Context()))
+}
+
+func writeReport(w http.ResponseWriter, report Report) {
+	code := http.StatusOK
+	if report.Status != StatusOk {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *HealthService) Run() {
+	fmt.Printf("health server listening on %s\n", h.server.Addr)
+	if err := h.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("health server stopped: %s\n", err.Error())
+	}
+}
+
+func (h *HealthService) Shutdown(ctx context.Context) error {
+	return h.server.Shutdown(ctx)
+}
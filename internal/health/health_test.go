@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"executor/internal/core/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestService() *HealthService {
+	return NewHealthService(&config.ExecutorConfig{Http: config.Http{CheckTimeout: time.Second}})
+}
+
+func TestReadinessOk(t *testing.T) {
+	h := newTestService()
+	h.AddReadinessCheck("postgres", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	h.handleReadiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("handleReadiness() code = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("json.Decode() error = %v", err)
+	}
+	if report.Checks["postgres"].Status != StatusOk {
+		t.Errorf("postgres status = %v, want %v", report.Checks["postgres"].Status, StatusOk)
+	}
+}
+
+func TestReadinessFail(t *testing.T) {
+	h := newTestService()
+	h.AddReadinessCheck("postgres", func(ctx context.Context) error { return nil })
+	h.AddReadinessCheck("docker", func(ctx context.Context) error { return errors.New("daemon unreachable") })
+
+	rec := httptest.NewRecorder()
+	h.handleReadiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("handleReadiness() code = %v, want %v", rec.Code, http.StatusServiceUnavailable)
+	}
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("json.Decode() error = %v", err)
+	}
+	if report.Status != StatusFail {
+		t.Errorf("report status = %v, want %v", report.Status, StatusFail)
+	}
+	if report.Checks["docker"].Error != "daemon unreachable" {
+		t.Errorf("docker error = %q, want %q", report.Checks["docker"].Error, "daemon unreachable")
+	}
+	if report.Checks["postgres"].Status != StatusOk {
+		t.Errorf("postgres status = %v, want %v", report.Checks["postgres"].Status, StatusOk)
+	}
+}
@@ -0,0 +1,313 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/retry"
+)
+
+var ErrNoPods = errors.New("no pods found for deployment")
+
+const (
+	labelManagedBy = "app.kubernetes.io/managed-by"
+	labelName      = "app.kubernetes.io/name"
+	labelBotID     = "executor/bot-id"
+	managedBy      = "executor"
+	containerName  = "bot"
+	filesVolume    = "files"
+)
+
+var invalidName = regexp.MustCompile(`[^a-z0-9-]+`)
+
+type KubernetesRuntime struct {
+	client     k8s.Interface
+	namespace  string
+	logLines   int64
+	pullPolicy corev1.PullPolicy
+}
+
+func NewKubernetesRuntime(cfg *config.ExecutorConfig) *KubernetesRuntime {
+	restCfg, err := restConfig(cfg.Kubernetes.Kubeconfig)
+	if err != nil {
+		panic(err)
+	}
+	clientset, err := k8s.NewForConfig(restCfg)
+	if err != nil {
+		panic(err)
+	}
+	return NewKubernetesRuntimeWithClient(clientset, cfg)
+}
+
+func NewKubernetesRuntimeWithClient(client k8s.Interface, cfg *config.ExecutorConfig) *KubernetesRuntime {
+	return &KubernetesRuntime{
+		client:     client,
+		namespace:  cfg.Kubernetes.Namespace,
+		logLines:   cfg.Kubernetes.LogLines,
+		pullPolicy: pullPolicy(cfg.Docker.PullPolicy),
+	}
+}
+
+func pullPolicy(policy string) corev1.PullPolicy {
+	switch policy {
+	case "always":
+		return corev1.PullAlways
+	case "never":
+		return corev1.PullNever
+	default:
+		return corev1.PullIfNotPresent
+	}
+}
+
+func restConfig(kubeconfig string) (*rest.Config, error) {
+	if kubeconfig == "" {
+		return rest.InClusterConfig()
+	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+func (r *KubernetesRuntime) Name() string {
+	return "kubernetes"
+}
+
+func (r *KubernetesRuntime) Hosts() []string {
+	return []string{r.namespace}
+}
+
+func (r *KubernetesRuntime) namespaceFor(bot models.Container) string {
+	if bot.DockerHost == "" {
+		return r.namespace
+	}
+	return bot.DockerHost
+}
+
+// objectName turns a container name into a DNS-1123 label.
+func objectName(name string) string {
+	n := invalidName.ReplaceAllString(strings.ToLower(name), "-")
+	n = strings.Trim(n, "-")
+	if len(n) > 63 {
+		n = strings.TrimRight(n[:63], "-")
+	}
+	return n
+}
+
+func secretName(name string) string {
+	return truncate(name, 57) + "-env"
+}
+
+func filesSecretName(name string) string {
+	return truncate(name, 57) + "-files"
+}
+
+func truncate(name string, n int) string {
+	if len(name) > n {
+		name = strings.TrimRight(name[:n], "-")
+	}
+	return name
+}
+
+// Create stores the bot env in a Secret and creates a Deployment scaled to
+// zero; Start and Stop scale it between one and zero replicas. Files go to a
+// second Secret that is only mounted, so their content never reaches the env.
+func (r *KubernetesRuntime) Create(ctx context.Context, bot models.Container, spec models.ContainerSpec) (*models.Container, error) {
+	namespace := r.namespaceFor(bot)
+	name := objectName(spec.Name)
+	labels := map[string]string{
+		labelManagedBy: managedBy,
+		labelName:      name,
+		labelBotID:     strconv.FormatInt(bot.BotID, 10),
+	}
+
+	env := make(map[string]string, len(spec.Env))
+	for _, kv := range spec.Env {
+		k, v, _ := strings.Cut(kv, "=")
+		env[k] = v
+	}
+	files := make(map[string][]byte, len(spec.Files))
+	mounts := make([]corev1.VolumeMount, 0, len(spec.Files))
+	for i, f := range spec.Files {
+		key := fmt.Sprintf("file-%d", i)
+		files[key] = f.Content
+		mounts = append(mounts, corev1.VolumeMount{Name: filesVolume, MountPath: f.Path, SubPath: key, ReadOnly: true})
+		if f.Env != "" {
+			env[f.Env] = f.Path
+		}
+	}
+	secrets := r.client.CoreV1().Secrets(namespace)
+	var volumes []corev1.Volume
+	if len(files) > 0 {
+		filesSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: filesSecretName(name), Namespace: namespace, Labels: labels},
+			Data:       files,
+			Type:       corev1.SecretTypeOpaque,
+		}
+		if _, err := secrets.Create(ctx, filesSecret, metav1.CreateOptions{}); err != nil {
+			return nil, err
+		}
+		volumes = []corev1.Volume{{
+			Name: filesVolume,
+			VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{
+				SecretName: filesSecret.Name,
+			}},
+		}}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName(name), Namespace: namespace, Labels: labels},
+		StringData: env,
+		Type:       corev1.SecretTypeOpaque,
+	}
+	if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		r.removeSecrets(context.WithoutCancel(ctx), namespace, name)
+		return nil, err
+	}
+
+	replicas := int32(0)
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{labelName: name}},
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels, Annotations: spec.Labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:            containerName,
+						Image:           spec.Image,
+						ImagePullPolicy: r.pullPolicy,
+						TTY:             true,
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: secret.Name},
+							},
+						}},
+						Resources:    resources(spec),
+						VolumeMounts: mounts,
+					}},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+	if _, err := r.client.AppsV1().Deployments(namespace).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+		r.removeSecrets(context.WithoutCancel(ctx), namespace, name)
+		return nil, err
+	}
+	bot.ContainerID = name
+	bot.DockerHost = namespace
+	return &bot, nil
+}
+
+func (r *KubernetesRuntime) removeSecrets(ctx context.Context, namespace, name string) error {
+	var errs []error
+	for _, secret := range []string{secretName(name), filesSecretName(name)} {
+		err := r.client.CoreV1().Secrets(namespace).Delete(ctx, secret, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func resources(spec models.ContainerSpec) corev1.ResourceRequirements {
+	limits := corev1.ResourceList{}
+	if spec.CPUs > 0 {
+		limits[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(spec.CPUs*1000), resource.DecimalSI)
+	}
+	if spec.MemoryBytes > 0 {
+		limits[corev1.ResourceMemory] = *resource.NewQuantity(spec.MemoryBytes, resource.BinarySI)
+	}
+	if len(limits) == 0 {
+		return corev1.ResourceRequirements{}
+	}
+	return corev1.ResourceRequirements{Limits: limits, Requests: limits}
+}
+
+func (r *KubernetesRuntime) scale(ctx context.Context, bot models.Container, replicas int32) error {
+	deployments := r.client.AppsV1().Deployments(r.namespaceFor(bot))
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := deployments.Get(ctx, bot.ContainerID, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		deployment.Spec.Replicas = &replicas
+		_, err = deployments.Update(ctx, deployment, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+func (r *KubernetesRuntime) Start(ctx context.Context, bot models.Container) error {
+	return r.scale(ctx, bot, 1)
+}
+
+func (r *KubernetesRuntime) Stop(ctx context.Context, bot models.Container) error {
+	return r.scale(ctx, bot, 0)
+}
+
+func (r *KubernetesRuntime) Remove(ctx context.Context, bot models.Container) error {
+	namespace := r.namespaceFor(bot)
+	propagation := metav1.DeletePropagationForeground
+	err := r.client.AppsV1().Deployments(namespace).Delete(ctx, bot.ContainerID, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return r.removeSecrets(ctx, namespace, bot.ContainerID)
+}
+
+func (r *KubernetesRuntime) Logs(ctx context.Context, bot models.Container, w io.Writer) error {
+	namespace := r.namespaceFor(bot)
+	pods, err := r.client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", labelName, bot.ContainerID),
+	})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrNoPods, namespace, bot.ContainerID)
+	}
+	opts := &corev1.PodLogOptions{Container: containerName}
+	if r.logLines > 0 {
+		opts.TailLines = &r.logLines
+	}
+	stream, err := r.client.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name, opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// Healthy reports whether the Deployment has an available replica, which
+// honours the pod's readiness probe when the image defines one.
+func (r *KubernetesRuntime) Healthy(ctx context.Context, bot models.Container) (bool, error) {
+	deployment, err := r.client.AppsV1().Deployments(r.namespaceFor(bot)).Get(ctx, bot.ContainerID, metav1.GetOptions{})
+	if err != nil {
+		return false, err
+	}
+	return deployment.Status.AvailableReplicas > 0, nil
+}
+
+func (r *KubernetesRuntime) Check(ctx context.Context) error {
+	_, err := r.client.CoreV1().Namespaces().Get(ctx, r.namespace, metav1.GetOptions{})
+	return err
+}
+
+func (r *KubernetesRuntime) IsNotFound(err error) bool {
+	return apierrors.IsNotFound(err) || errors.Is(err, ErrNoPods)
+}
@@ -0,0 +1,145 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestRuntime() (*KubernetesRuntime, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset()
+	cfg := &config.ExecutorConfig{Kubernetes: config.Kubernetes{Namespace: "bots", LogLines: 10}}
+	return NewKubernetesRuntimeWithClient(clientset, cfg), clientset
+}
+
+func TestLifecycle(t *testing.T) {
+	ctx := context.Background()
+	r, clientset := newTestRuntime()
+	spec := models.ContainerSpec{
+		Name:   "tg-Support_Bot 2026",
+		Image:  "registry/bot:1",
+		Env:    []string{"TELEGRAM_BOT_TOKEN=secret", "SEARCH_URL=http://search?a=b"},
+		Labels: map[string]string{"co.elastic.logs/enabled": "true"},
+	}
+
+	bot, err := r.Create(ctx, models.Container{BotID: 7}, spec)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if bot.ContainerID != "tg-support-bot-2026" || bot.DockerHost != "bots" {
+		t.Errorf("Create() = %v/%v, want bots/tg-support-bot-2026", bot.DockerHost, bot.ContainerID)
+	}
+
+	secret, err := clientset.CoreV1().Secrets("bots").Get(ctx, "tg-support-bot-2026-env", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Secrets.Get() error = %v", err)
+	}
+	if secret.StringData["SEARCH_URL"] != "http://search?a=b" {
+		t.Errorf("secret SEARCH_URL = %q, want %q", secret.StringData["SEARCH_URL"], "http://search?a=b")
+	}
+
+	deployment, err := clientset.AppsV1().Deployments("bots").Get(ctx, bot.ContainerID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Deployments.Get() error = %v", err)
+	}
+	if *deployment.Spec.Replicas != 0 {
+		t.Errorf("replicas after Create() = %d, want 0", *deployment.Spec.Replicas)
+	}
+	if deployment.Spec.Template.Spec.Containers[0].Image != "registry/bot:1" {
+		t.Errorf("image = %q, want %q", deployment.Spec.Template.Spec.Containers[0].Image, "registry/bot:1")
+	}
+
+	if err := r.Start(ctx, *bot); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	deployment, _ = clientset.AppsV1().Deployments("bots").Get(ctx, bot.ContainerID, metav1.GetOptions{})
+	if *deployment.Spec.Replicas != 1 {
+		t.Errorf("replicas after Start() = %d, want 1", *deployment.Spec.Replicas)
+	}
+
+	if err := r.Stop(ctx, *bot); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	deployment, _ = clientset.AppsV1().Deployments("bots").Get(ctx, bot.ContainerID, metav1.GetOptions{})
+	if *deployment.Spec.Replicas != 0 {
+		t.Errorf("replicas after Stop() = %d, want 0", *deployment.Spec.Replicas)
+	}
+
+	if err := r.Remove(ctx, *bot); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := clientset.AppsV1().Deployments("bots").Get(ctx, bot.ContainerID, metav1.GetOptions{}); !r.IsNotFound(err) {
+		t.Errorf("Deployments.Get() after Remove() error = %v, want not found", err)
+	}
+	if _, err := clientset.CoreV1().Secrets("bots").Get(ctx, "tg-support-bot-2026-env", metav1.GetOptions{}); !r.IsNotFound(err) {
+		t.Errorf("Secrets.Get() after Remove() error = %v, want not found", err)
+	}
+	if err := r.Start(ctx, *bot); !r.IsNotFound(err) {
+		t.Errorf("Start() after Remove() error = %v, want not found", err)
+	}
+}
+
+func TestFilesSecret(t *testing.T) {
+	ctx := context.Background()
+	r, clientset := newTestRuntime()
+	spec := models.ContainerSpec{
+		Name:  "tg-bot",
+		Image: "registry/bot:1",
+		Env:   []string{"SEARCH_URL=http://search"},
+		Files: []models.ContainerFile{{Path: "/run/secrets/TELEGRAM_BOT_TOKEN", Env: "TELEGRAM_BOT_TOKEN_FILE", Content: []byte("secret")}},
+	}
+	bot, err := r.Create(ctx, models.Container{BotID: 7}, spec)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	env, _ := clientset.CoreV1().Secrets("bots").Get(ctx, "tg-bot-env", metav1.GetOptions{})
+	if len(env.Data) != 0 || env.StringData["TELEGRAM_BOT_TOKEN_FILE"] != "/run/secrets/TELEGRAM_BOT_TOKEN" {
+		t.Errorf("env secret = %v %v, want only the env and the file path", env.StringData, env.Data)
+	}
+	files, err := clientset.CoreV1().Secrets("bots").Get(ctx, "tg-bot-files", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Secrets.Get() error = %v", err)
+	}
+	if string(files.Data["file-0"]) != "secret" {
+		t.Errorf("files secret file-0 = %q, want %q", files.Data["file-0"], "secret")
+	}
+	deployment, _ := clientset.AppsV1().Deployments("bots").Get(ctx, bot.ContainerID, metav1.GetOptions{})
+	if got := deployment.Spec.Template.Spec.Volumes[0].Secret.SecretName; got != "tg-bot-files" {
+		t.Errorf("volume secret = %q, want %q", got, "tg-bot-files")
+	}
+
+	if err := r.Remove(ctx, *bot); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := clientset.CoreV1().Secrets("bots").Get(ctx, "tg-bot-files", metav1.GetOptions{}); !r.IsNotFound(err) {
+		t.Errorf("Secrets.Get() after Remove() error = %v, want not found", err)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	ctx := context.Background()
+	r, clientset := newTestRuntime()
+	bot := models.Container{ContainerID: "tg-bot", DockerHost: "bots"}
+
+	var buf bytes.Buffer
+	if err := r.Logs(ctx, bot, &buf); !r.IsNotFound(err) {
+		t.Errorf("Logs() without pods error = %v, want not found", err)
+	}
+
+	clientset.CoreV1().Pods("bots").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "tg-bot-abc", Namespace: "bots", Labels: map[string]string{labelName: "tg-bot"}},
+	}, metav1.CreateOptions{})
+	if err := r.Logs(ctx, bot, &buf); err != nil {
+		t.Fatalf("Logs() error = %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Errorf("Logs() wrote nothing")
+	}
+}
@@ -0,0 +1,148 @@
+package leader
+
+import (
+	"context"
+	"executor/internal/core/config"
+	"executor/internal/core/ports"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type LeaderService struct {
+	repo      ports.LeaseRepository
+	name      string
+	id        string
+	interval  time.Duration
+	mu        sync.Mutex
+	elected   []func(ctx context.Context)
+	revoked   []func()
+	isLeader  atomic.Bool
+	lease     ports.Lease
+	cancelRun context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewLeaderService(repo ports.LeaseRepository, cfg *config.ExecutorConfig) *LeaderService {
+	return &LeaderService{
+		repo:     repo,
+		name:     cfg.Leader.Name,
+		id:       cfg.Executor.ID,
+		interval: cfg.Leader.Interval,
+	}
+}
+
+// OnElected registers fn to run in its own goroutine each time leadership is
+// gained. Its ctx is cancelled as soon as leadership is lost.
+func (l *LeaderService) OnElected(fn func(ctx context.Context)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.elected = append(l.elected, fn)
+}
+
+func (l *LeaderService) OnRevoked(fn func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.revoked = append(l.revoked, fn)
+}
+
+// Job runs fn every interval for as long as this executor is the leader.
+func (l *LeaderService) Job(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	l.OnElected(func(ctx context.Context) {
+		fmt.Printf("[leader] job %s started\n", name)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("[leader] job %s failed: %s\n", name, err.Error())
+			}
+			select {
+			case <-ctx.Done():
+				fmt.Printf("[leader] job %s stopped\n", name)
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func (l *LeaderService) IsLeader() bool {
+	return l.isLeader.Load()
+}
+
+func (l *LeaderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+	for {
+		l.tick(ctx)
+		select {
+		case <-ctx.Done():
+			l.revoke()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (l *LeaderService) tick(ctx context.Context) {
+	if l.IsLeader() {
+		checkCtx, cancel := context.WithTimeout(ctx, l.interval)
+		defer cancel()
+		if err := l.lease.Alive(checkCtx); err != nil && ctx.Err() == nil {
+			fmt.Printf("[leader] %s lost leadership of %s: %s\n", l.id, l.name, err.Error())
+			l.revoke()
+		}
+		return
+	}
+	lease, err := l.repo.TryLock(ctx, l.name)
+	if err != nil {
+		if ctx.Err() == nil {
+			fmt.Printf("[leader] could not acquire %s: %s\n", l.name, err.Error())
+		}
+		return
+	}
+	if lease == nil {
+		return
+	}
+	l.elect(ctx, lease)
+}
+
+func (l *LeaderService) elect(ctx context.Context, lease ports.Lease) {
+	runCtx, cancel := context.WithCancel(ctx)
+	l.lease = lease
+	l.cancelRun = cancel
+	l.isLeader.Store(true)
+	fmt.Printf("[leader] %s elected leader of %s\n", l.id, l.name)
+
+	l.mu.Lock()
+	elected := append([]func(context.Context){}, l.elected...)
+	l.mu.Unlock()
+	for _, fn := range elected {
+		l.wg.Add(1)
+		go func(fn func(context.Context)) {
+			defer l.wg.Done()
+			fn(runCtx)
+		}(fn)
+	}
+}
+
+func (l *LeaderService) revoke() {
+	if !l.isLeader.Swap(false) {
+		return
+	}
+	l.cancelRun()
+	l.wg.Wait()
+	if err := l.lease.Release(); err != nil {
+		fmt.Printf("[leader] could not release %s: %s\n", l.name, err.Error())
+	}
+	l.lease = nil
+
+	l.mu.Lock()
+	revoked := append([]func(){}, l.revoked...)
+	l.mu.Unlock()
+	for _, fn := range revoked {
+		fn()
+	}
+	fmt.Printf("[leader] %s stepped down as leader of %s\n", l.id, l.name)
+}
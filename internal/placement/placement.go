@@ -0,0 +1,86 @@
+package placement
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+)
+
+var (
+	ErrNoCandidates      = errors.New("no candidates available for placement")
+	ErrUnsupportedPolicy = errors.New("unsupported placement policy")
+)
+
+const (
+	LeastLoaded = "least-loaded"
+	Hash        = "hash"
+)
+
+type Candidate struct {
+	ID   string
+	Load int64
+}
+
+type Strategy interface {
+	Place(bot_id int64, candidates []Candidate) (string, error)
+}
+
+func NewStrategy(policy string) (Strategy, error) {
+	switch policy {
+	case LeastLoaded:
+		return leastLoaded{}, nil
+	case Hash:
+		return rendezvous{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedPolicy, policy)
+	}
+}
+
+type leastLoaded struct{}
+
+// ties are broken by rendezvous hashing so every executor picks the same candidate
+func (leastLoaded) Place(bot_id int64, candidates []Candidate) (string, error) {
+	if len(candidates) == 0 {
+		return "", ErrNoCandidates
+	}
+	var (
+		best      Candidate
+		bestScore uint64
+	)
+	for i, c := range candidates {
+		score := weight(c.ID, bot_id)
+		if i == 0 || c.Load < best.Load || (c.Load == best.Load && score > bestScore) {
+			best, bestScore = c, score
+		}
+	}
+	return best.ID, nil
+}
+
+type rendezvous struct{}
+
+// highest random weight hashing only moves the bots of a candidate that
+// joins or leaves
+func (rendezvous) Place(bot_id int64, candidates []Candidate) (string, error) {
+	if len(candidates) == 0 {
+		return "", ErrNoCandidates
+	}
+	var (
+		best      string
+		bestScore uint64
+	)
+	for i, c := range candidates {
+		if score := weight(c.ID, bot_id); i == 0 || score > bestScore {
+			best, bestScore = c.ID, score
+		}
+	}
+	return best, nil
+}
+
+func weight(id string, bot_id int64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(bot_id, 10)))
+	return h.Sum64()
+}
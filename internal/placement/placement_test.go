@@ -0,0 +1,49 @@
+package placement
+
+import "testing"
+
+func TestLeastLoaded(t *testing.T) {
+	s, err := NewStrategy(LeastLoaded)
+	if err != nil {
+		t.Fatalf("NewStrategy() error = %v", err)
+	}
+	got, err := s.Place(42, []Candidate{{ID: "a", Load: 3}, {ID: "b", Load: 1}, {ID: "c", Load: 2}})
+	if err != nil {
+		t.Fatalf("Place() error = %v", err)
+	}
+	if got != "b" {
+		t.Errorf("Place() = %v, want %v", got, "b")
+	}
+}
+
+func TestHashIsStable(t *testing.T) {
+	s, err := NewStrategy(Hash)
+	if err != nil {
+		t.Fatalf("NewStrategy() error = %v", err)
+	}
+	candidates := []Candidate{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	reversed := []Candidate{{ID: "c"}, {ID: "b"}, {ID: "a"}}
+	moved := 0
+	for bot_id := int64(0); bot_id < 100; bot_id++ {
+		first, _ := s.Place(bot_id, candidates)
+		second, _ := s.Place(bot_id, reversed)
+		if first != second {
+			t.Errorf("Place(%d) depends on candidate order: %v != %v", bot_id, first, second)
+		}
+		// removing a candidate only moves the bots it owned
+		without, _ := s.Place(bot_id, candidates[:2])
+		if first != "c" && without != first {
+			moved++
+		}
+	}
+	if moved != 0 {
+		t.Errorf("Place() moved %d bots not owned by the removed candidate", moved)
+	}
+}
+
+func TestNoCandidates(t *testing.T) {
+	s, _ := NewStrategy(Hash)
+	if _, err := s.Place(1, nil); err != ErrNoCandidates {
+		t.Errorf("Place() error = %v, want %v", err, ErrNoCandidates)
+	}
+}
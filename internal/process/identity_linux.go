@@ -0,0 +1,27 @@
+//go:build linux
+
+package process
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// processStart returns the start time of pid in clock ticks since boot. A
+// pid and its start time together identify one process across pid reuse
+// and reboots.
+func processStart(pid int) (string, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return "", err
+	}
+	// the command name may contain spaces and parentheses; fields resume
+	// after the last ')' at field 3, and starttime is field 22
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 20 {
+		return "", fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	return fields[19], nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package process
+
+import "errors"
+
+var errNoProcessStart = errors.New("process start time is not available on this platform")
+
+// without /proc a pid cannot be told apart from a reused one, so pid files
+// left by a previous executor run are never trusted
+func processStart(pid int) (string, error) {
+	return "", errNoProcessStart
+}
@@ -0,0 +1,294 @@
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrProcessNotFound = errors.New("no such process")
+	ErrProcessExited   = errors.New("process exited")
+	ErrBinaryNotFound  = errors.New("bot binary not found")
+)
+
+const hostName = "local"
+
+type process struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// ProcessRuntime runs bots as child processes of the executor for local
+// development. ContainerID holds a handle name; the live PID of each bot and
+// its start time are tracked in <state_dir>/<handle>.pid next to its spec and
+// log file, so a restarted executor can still stop processes it launched
+// earlier without signalling an unrelated process that reused the PID.
+type ProcessRuntime struct {
+	binary      string
+	args        []string
+	stateDir    string
+	stopTimeout time.Duration
+	mu          sync.Mutex
+	running     map[string]*process
+}
+
+func NewProcessRuntime(cfg *config.ExecutorConfig) *ProcessRuntime {
+	if err := os.MkdirAll(cfg.Process.StateDir, 0o700); err != nil {
+		panic(err)
+	}
+	return &ProcessRuntime{
+		binary:      cfg.Process.Binary,
+		args:        cfg.Process.Args,
+		stateDir:    cfg.Process.StateDir,
+		stopTimeout: time.Duration(cfg.Docker.Timeout) * time.Second,
+		running:     make(map[string]*process),
+	}
+}
+
+func (r *ProcessRuntime) Name() string {
+	return "process"
+}
+
+func (r *ProcessRuntime) Hosts() []string {
+	return []string{hostName}
+}
+
+func (r *ProcessRuntime) path(handle, ext string) string {
+	return filepath.Join(r.stateDir, handle+ext)
+}
+
+func (r *ProcessRuntime) Create(ctx context.Context, bot models.Container, spec models.ContainerSpec) (*models.Container, error) {
+	handle := spec.Name
+	// files live next to the spec; the bot finds them through their env
+	for _, f := range spec.Files {
+		path := filepath.Join(r.path(handle, ".files"), filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, f.Content, 0o600); err != nil {
+			return nil, err
+		}
+		if f.Env != "" {
+			spec.Env = append(spec.Env, f.Env+"="+path)
+		}
+	}
+	spec.Files = nil
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	// the spec carries secrets from the env
+	if err := os.WriteFile(r.path(handle, ".json"), data, 0o600); err != nil {
+		return nil, err
+	}
+	bot.ContainerID = handle
+	bot.DockerHost = hostName
+	return &bot, nil
+}
+
+func (r *ProcessRuntime) readSpec(handle string) (*models.ContainerSpec, error) {
+	data, err := os.ReadFile(r.path(handle, ".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrProcessNotFound, handle)
+		}
+		return nil, err
+	}
+	var spec models.ContainerSpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func (r *ProcessRuntime) Start(ctx context.Context, bot models.Container) error {
+	handle := bot.ContainerID
+	spec, err := r.readSpec(handle)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pid, ok := r.livePID(handle); ok {
+		fmt.Printf("[%s] process already running (pid: %d)\n", handle, pid)
+		return nil
+	}
+	logFile, err := os.OpenFile(r.path(handle, ".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	// not bound to ctx: the bot must outlive the message that started it
+	cmd := exec.Command(r.binary, r.args...)
+	cmd.Env = append(os.Environ(), spec.Env...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrBinaryNotFound, r.binary)
+		}
+		return err
+	}
+	p := &process{cmd: cmd, done: make(chan struct{})}
+	r.running[handle] = p
+	go func() {
+		cmd.Wait()
+		logFile.Close()
+		close(p.done)
+		r.mu.Lock()
+		if r.running[handle] == p {
+			delete(r.running, handle)
+			os.Remove(r.path(handle, ".pid"))
+		}
+		r.mu.Unlock()
+	}()
+	pid := cmd.Process.Pid
+	start, err := processStart(pid)
+	if err != nil {
+		fmt.Printf("[%s] %s, a restarted executor will not adopt it\n", handle, err.Error())
+	}
+	if err := os.WriteFile(r.path(handle, ".pid"), []byte(fmt.Sprintf("%d %s", pid, start)), 0o600); err != nil {
+		return err
+	}
+	fmt.Printf("[%s] process started (pid: %d)\n", handle, cmd.Process.Pid)
+	return nil
+}
+
+// livePID reports the PID of a running bot, consulting the pid file for
+// processes started by a previous executor run. Such a process is only
+// adopted when its start time still matches the recorded one; otherwise the
+// pid file is stale and removed.
+func (r *ProcessRuntime) livePID(handle string) (int, bool) {
+	if p, ok := r.running[handle]; ok {
+		return p.cmd.Process.Pid, true
+	}
+	data, err := os.ReadFile(r.path(handle, ".pid"))
+	if err != nil {
+		return 0, false
+	}
+	pidField, recorded, _ := strings.Cut(strings.TrimSpace(string(data)), " ")
+	pid, err := strconv.Atoi(pidField)
+	if err != nil {
+		return 0, false
+	}
+	if start, err := processStart(pid); err != nil || recorded == "" || start != recorded {
+		os.Remove(r.path(handle, ".pid"))
+		return 0, false
+	}
+	return pid, true
+}
+
+func (r *ProcessRuntime) Healthy(ctx context.Context, bot models.Container) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.livePID(bot.ContainerID); !ok {
+		return false, fmt.Errorf("%w: %s", ErrProcessExited, bot.ContainerID)
+	}
+	return true, nil
+}
+
+func (r *ProcessRuntime) Stop(ctx context.Context, bot models.Container) error {
+	handle := bot.ContainerID
+	if _, err := os.Stat(r.path(handle, ".json")); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrProcessNotFound, handle)
+	}
+	r.mu.Lock()
+	pid, ok := r.livePID(handle)
+	p := r.running[handle]
+	r.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if err := signalProcess(pid, syscall.SIGTERM); err != nil {
+		return nil
+	}
+	if !r.wait(ctx, p, proc) {
+		fmt.Printf("[%s] process did not exit in %s, killing (pid: %d)\n", handle, r.stopTimeout, pid)
+		signalProcess(pid, syscall.SIGKILL)
+	}
+	os.Remove(r.path(handle, ".pid"))
+	return nil
+}
+
+func (r *ProcessRuntime) wait(ctx context.Context, p *process, proc *os.Process) bool {
+	timer := time.NewTimer(r.stopTimeout)
+	defer timer.Stop()
+	if p != nil {
+		select {
+		case <-p.done:
+			return true
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		return false
+	}
+	// not our child: poll until the pid disappears
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if proc.Signal(syscall.Signal(0)) != nil {
+				return true
+			}
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (r *ProcessRuntime) Remove(ctx context.Context, bot models.Container) error {
+	if err := r.Stop(ctx, bot); err != nil {
+		return err
+	}
+	for _, ext := range []string{".json", ".log", ".pid"} {
+		if err := os.Remove(r.path(bot.ContainerID, ext)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.RemoveAll(r.path(bot.ContainerID, ".files"))
+}
+
+func (r *ProcessRuntime) Logs(ctx context.Context, bot models.Container, w io.Writer) error {
+	f, err := os.Open(r.path(bot.ContainerID, ".log"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrProcessNotFound, bot.ContainerID)
+		}
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+func (r *ProcessRuntime) Check(ctx context.Context) error {
+	if _, err := exec.LookPath(r.binary); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrBinaryNotFound, r.binary, err)
+	}
+	return nil
+}
+
+func (r *ProcessRuntime) IsNotFound(err error) bool {
+	return errors.Is(err, ErrProcessNotFound)
+}
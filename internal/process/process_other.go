@@ -0,0 +1,22 @@
+//go:build !unix
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func signalProcess(pid int, sig syscall.Signal) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	if sig == syscall.SIGKILL {
+		return proc.Kill()
+	}
+	return proc.Signal(sig)
+}
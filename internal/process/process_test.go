@@ -0,0 +1,97 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"executor/internal/core/config"
+	"executor/internal/core/models"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	ctx := context.Background()
+	r := NewProcessRuntime(&config.ExecutorConfig{
+		Docker:  config.Docker{Timeout: 1},
+		Process: config.Process{Binary: "sh", Args: []string{"-c", "echo token=$TELEGRAM_BOT_TOKEN; sleep 30"}, StateDir: t.TempDir()},
+	})
+	if err := r.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	bot, err := r.Create(ctx, models.Container{BotID: 1}, models.ContainerSpec{Name: "tg-bot", Env: []string{"TELEGRAM_BOT_TOKEN=secret"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := r.Start(ctx, *bot); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	r.mu.Lock()
+	pid, ok := r.livePID(bot.ContainerID)
+	r.mu.Unlock()
+	if !ok || pid == 0 {
+		t.Fatalf("livePID() = %v, %v, want running", pid, ok)
+	}
+
+	var buf bytes.Buffer
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(buf.String(), "token=secret") && time.Now().Before(deadline) {
+		buf.Reset()
+		if err := r.Logs(ctx, *bot, &buf); err != nil {
+			t.Fatalf("Logs() error = %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "token=secret") {
+		t.Errorf("Logs() = %q, want the bot env to reach the process", buf.String())
+	}
+
+	if err := r.Stop(ctx, *bot); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	r.mu.Lock()
+	_, ok = r.livePID(bot.ContainerID)
+	r.mu.Unlock()
+	if ok {
+		t.Errorf("livePID() after Stop() reports a running process")
+	}
+
+	if err := r.Remove(ctx, *bot); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := r.Start(ctx, *bot); !r.IsNotFound(err) {
+		t.Errorf("Start() after Remove() error = %v, want not found", err)
+	}
+}
+
+func TestLivePIDRejectsReusedPID(t *testing.T) {
+	r := NewProcessRuntime(&config.ExecutorConfig{Process: config.Process{Binary: "sh", StateDir: t.TempDir()}})
+	// a pid that is alive but was recorded with another start time
+	stale := fmt.Sprintf("%d 1", os.Getpid())
+	if err := os.WriteFile(r.path("tg-bot", ".pid"), []byte(stale), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if pid, ok := r.livePID("tg-bot"); ok {
+		t.Errorf("livePID() = %d, true for a reused pid, want false", pid)
+	}
+	if _, err := os.Stat(r.path("tg-bot", ".pid")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("stale pid file was kept: %v", err)
+	}
+
+	start, err := processStart(os.Getpid())
+	if err != nil {
+		t.Skip(err)
+	}
+	os.WriteFile(r.path("tg-bot", ".pid"), []byte(fmt.Sprintf("%d %s", os.Getpid(), start)), 0o600)
+	if pid, ok := r.livePID("tg-bot"); !ok || pid != os.Getpid() {
+		t.Errorf("livePID() = %d, %v, want %d adopted", pid, ok, os.Getpid())
+	}
+}
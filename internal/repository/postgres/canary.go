@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/ports"
+	pu "executor/pkg/postgres_utils"
+)
+
+var ErrCanaryNotFound = ports.ErrCanaryNotFound
+
+func (repo *PostgresRepository) GetCanaryRollout(ctx context.Context, image string) (*dto.CanaryDbo, error) {
+	rows, err := withRetry(ctx, repo, func() ([]dto.CanaryDbo, error) {
+		return pu.Dispatch[dto.CanaryDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT c.image, c.status, c.updated_at
+			FROM canary_rollouts c
+			WHERE c.image = $1::text;
+			`,
+			image,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrCanaryNotFound
+	}
+	return &rows[0], nil
+}
+
+func (repo *PostgresRepository) SetCanaryRollout(ctx context.Context, image, status string) error {
+	_, err := pu.Dispatch[dto.CanaryDbo](
+		ctx,
+		repo.db,
+		`
+		INSERT INTO canary_rollouts (image, status, updated_at)
+		VALUES ($1::text, $2::text, now())
+		ON CONFLICT (image) DO UPDATE
+		SET status = EXCLUDED.status,
+		    updated_at = now();
+		`,
+		image,
+		status,
+	)
+	return err
+}
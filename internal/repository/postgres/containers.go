@@ -4,30 +4,40 @@ import (
 	"context"
 	"errors"
 	"executor/internal/application/dto"
+	"executor/internal/core/ports"
 	pu "executor/pkg/postgres_utils"
 	"fmt"
 )
 
 var (
-	ErrBotNotFound   = errors.New("could not find bot_container by id")
-	ErrBotsNotFound  = errors.New("could not find any bot_containers")
+	ErrBotNotFound   = ports.ErrBotNotFound
+	ErrBotsNotFound  = ports.ErrBotsNotFound
 	ErrBotNotCreated = errors.New("could not create bot_container")
 	ErrBotNotUpdated = errors.New("could not update bot_container")
 	ErrBotNotDeleted = errors.New("could not delete bot_container")
+	ErrBotExists     = errors.New("active bot_container already exists")
 )
 
+// containerColumns selects a bot_containers row b into dto.ContainerDbo.
+const containerColumns = `b.id, b.container_name, b.port, b.container_id, b.bot_id, b.project_id, b.user_id, b.name, b.description, b.icon, b.state, b.api_token,
+			COALESCE(b.api_token_key_id, '') AS api_token_key_id, COALESCE(b.executor_id, '') AS executor_id, COALESCE(b.docker_host, '') AS docker_host,
+			COALESCE(b.image, '') AS image, COALESCE(b.previous_image, '') AS previous_image, COALESCE(b.image_variant, '') AS image_variant,
+			COALESCE(b.spec_hash, '') AS spec_hash, COALESCE(b.kind, '') AS kind, b.telegram`
+
 func (repo *PostgresRepository) GetContainerById(ctx context.Context, id int64) (*dto.ContainerDbo, error) {
-	rows, err := pu.Dispatch[dto.ContainerDbo](
-		ctx,
-		repo.db,
-		`
-		SELECT b.id, b.container_name, b.port, b.container_id, b.bot_id, b.project_id, b.user_id, b.name, b.description, b.icon, b.state, b.api_token
-		FROM bot_containers b
-		WHERE b.id = $1::bigint
-		  AND b.deleted_at IS NULL;
-		`,
-		id,
-	)
+	rows, err := withRetry(ctx, repo, func() ([]dto.ContainerDbo, error) {
+		return pu.Dispatch[dto.ContainerDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT `+containerColumns+`
+			FROM bot_containers b
+			WHERE b.id = $1::bigint
+			  AND b.deleted_at IS NULL;
+			`,
+			id,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -38,17 +48,19 @@ func (repo *PostgresRepository) GetContainerById(ctx context.Context, id int64)
 }
 
 func (repo *PostgresRepository) GetContainerByContainerId(ctx context.Context, container_id string) (*dto.ContainerDbo, error) {
-	rows, err := pu.Dispatch[dto.ContainerDbo](
-		ctx,
-		repo.db,
-		`
-		SELECT b.id, b.container_name, b.port, b.container_id, b.bot_id, b.project_id, b.user_id, b.name, b.description, b.icon, b.state, b.api_token
-		FROM bot_containers b
-		WHERE b.container_id = $1::text
-		  AND b.deleted_at IS NULL;
-		`,
-		container_id,
-	)
+	rows, err := withRetry(ctx, repo, func() ([]dto.ContainerDbo, error) {
+		return pu.Dispatch[dto.ContainerDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT `+containerColumns+`
+			FROM bot_containers b
+			WHERE b.container_id = $1::text
+			  AND b.deleted_at IS NULL;
+			`,
+			container_id,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -59,21 +71,23 @@ func (repo *PostgresRepository) GetContainerByContainerId(ctx context.Context, c
 }
 
 func (repo *PostgresRepository) GetContainerByBotInfo(ctx context.Context, bot dto.ContainerDbo) (*dto.ContainerDbo, error) {
-	rows, err := pu.Dispatch[dto.ContainerDbo](
-		ctx,
-		repo.db,
-		`
-		SELECT b.id, b.container_name, b.port, b.container_id, b.bot_id, b.project_id, b.user_id, b.name, b.description, b.icon, b.state, b.api_token
-		FROM bot_containers b
-		WHERE b.bot_id = $1::bigint
-		  AND b.project_id = $2::bigint
-		  AND b.user_id = $3::bigint
-		  AND b.deleted_at IS NULL;
-		`,
-		bot.BotID,
-		bot.ProjectID,
-		bot.UserID,
-	)
+	rows, err := withRetry(ctx, repo, func() ([]dto.ContainerDbo, error) {
+		return pu.Dispatch[dto.ContainerDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT `+containerColumns+`
+			FROM bot_containers b
+			WHERE b.bot_id = $1::bigint
+			  AND b.project_id = $2::bigint
+			  AND b.user_id = $3::bigint
+			  AND b.deleted_at IS NULL;
+			`,
+			bot.BotID,
+			bot.ProjectID,
+			bot.UserID,
+		)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -84,26 +98,126 @@ func (repo *PostgresRepository) GetContainerByBotInfo(ctx context.Context, bot d
 }
 
 func (repo *PostgresRepository) GetAllBots(ctx context.Context) ([]dto.ContainerDbo, error) {
-	rows, err := pu.Dispatch[dto.ContainerDbo](
+	rows, err := withRetry(ctx, repo, func() ([]dto.ContainerDbo, error) {
+		return pu.Dispatch[dto.ContainerDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT `+containerColumns+`
+			FROM bot_containers b
+			WHERE b.deleted_at IS NULL;
+			`,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrBotsNotFound
+	}
+	return rows, nil
+}
+
+func (repo *PostgresRepository) GetBotsByExecutor(ctx context.Context, executor_id string) ([]dto.ContainerDbo, error) {
+	rows, err := withRetry(ctx, repo, func() ([]dto.ContainerDbo, error) {
+		return pu.Dispatch[dto.ContainerDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT `+containerColumns+`
+			FROM bot_containers b
+			WHERE b.executor_id = $1::text
+			  AND b.deleted_at IS NULL;
+			`,
+			executor_id,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrBotsNotFound
+	}
+	return rows, nil
+}
+
+func (repo *PostgresRepository) SetBotExecutor(ctx context.Context, id int64, executor_id string) error {
+	_, err := pu.Dispatch[dto.ContainerDbo](
 		ctx,
 		repo.db,
 		`
-		SELECT b.id, b.container_name, b.port, b.bot_id, b.container_id, b.project_id, b.user_id, b.name, b.description, b.icon, b.state, b.api_token
-		FROM bot_containers b
-		WHERE b.deleted_at IS NULL;
+		UPDATE bot_containers
+		SET executor_id = $1::text
+		WHERE id = $2::bigint;
 		`,
+		executor_id,
+		id,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if len(rows) == 0 {
-		return nil, ErrBotsNotFound
+	return nil
+}
+
+func (repo *PostgresRepository) SetBotVariant(ctx context.Context, id int64, variant string) error {
+	_, err := pu.Dispatch[dto.ContainerDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE bot_containers
+		SET image_variant = $1::text
+		WHERE id = $2::bigint;
+		`,
+		variant,
+		id,
+	)
+	if err != nil {
+		return err
 	}
-	return rows, nil
+	return nil
+}
+
+func (repo *PostgresRepository) SetBotImage(ctx context.Context, id int64, image, previous_image, variant string) error {
+	_, err := pu.Dispatch[dto.ContainerDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE bot_containers
+		SET image = $1::text,
+		    previous_image = NULLIF($2::text, ''),
+		    image_variant = $3::text
+		WHERE id = $4::bigint;
+		`,
+		image,
+		previous_image,
+		variant,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (repo *PostgresRepository) SetBotToken(ctx context.Context, id int64, api_token, key_id string) error {
+	_, err := pu.Dispatch[dto.ContainerDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE bot_containers
+		SET api_token = $1::text,
+		    api_token_key_id = NULLIF($2::text, '')
+		WHERE id = $3::bigint
+		  AND deleted_at IS NULL;
+		`,
+		api_token,
+		key_id,
+		id,
+	)
+	return err
 }
 
 func (repo *PostgresRepository) CreateBot(ctx context.Context, bot dto.ContainerDbo) (int64, error) {
-	fmt.Println(bot)
 	rows, err := pu.Dispatch[dto.ContainerDbo](
 		ctx,
 		repo.db,
@@ -119,7 +233,16 @@ func (repo *PostgresRepository) CreateBot(ctx context.Context, bot dto.Container
 			description,
 			icon,
 			state,
-			api_token
+			api_token,
+			executor_id,
+			docker_host,
+			image,
+			previous_image,
+			image_variant,
+			spec_hash,
+			kind,
+			telegram,
+			api_token_key_id
 		)
 		VALUES (
 			$1::text,
@@ -132,9 +255,18 @@ func (repo *PostgresRepository) CreateBot(ctx context.Context, bot dto.Container
 			$8::text,
 			$9::text,
 			$10::text,
-			$11::text
+			$11::text,
+			NULLIF($12::text, ''),
+			NULLIF($13::text, ''),
+			NULLIF($14::text, ''),
+			NULLIF($15::text, ''),
+			NULLIF($16::text, ''),
+			NULLIF($17::text, ''),
+			NULLIF($18::text, ''),
+			NULLIF($19::text, '')::jsonb,
+			NULLIF($20::text, '')
 		)
-		RETURNING *;
+		RETURNING id;
 		`,
 		bot.ContainerName,
 		bot.Port,
@@ -147,8 +279,20 @@ func (repo *PostgresRepository) CreateBot(ctx context.Context, bot dto.Container
 		bot.Icon,
 		bot.State,
 		bot.ApiToken,
+		bot.ExecutorID,
+		bot.DockerHost,
+		bot.Image,
+		bot.PreviousImage,
+		bot.Variant,
+		bot.SpecHash,
+		bot.Kind,
+		string(bot.Telegram),
+		bot.ApiTokenKeyID,
 	)
 	if err != nil {
+		if pu.IsUniqueViolation(err) {
+			return 0, fmt.Errorf("%w: bot_id: %d", ErrBotExists, bot.BotID)
+		}
 		return 0, err
 	}
 	if len(rows) == 0 {
@@ -165,17 +309,19 @@ func (repo *PostgresRepository) UpdateBotById(ctx context.Context, bot dto.Conta
     UPDATE bot_containers
 		SET name = $1::text,
 		    description = $2::text,
-		    icon = $3::text
-		    api_token = $4::text
-		WHERE id = $5::bigint
+		    icon = $3::text,
+		    api_token = $4::text,
+		    api_token_key_id = NULLIF($5::text, '')
+		WHERE id = $6::bigint
 		  AND deleted_at IS NULL
-		RETURNING *;
+		RETURNING id, container_name, port, container_id, bot_id, project_id, user_id, name, description, icon, state, api_token, COALESCE(api_token_key_id, '') AS api_token_key_id, COALESCE(executor_id, '') AS executor_id, COALESCE(docker_host, '') AS docker_host, COALESCE(image, '') AS image, COALESCE(previous_image, '') AS previous_image, COALESCE(image_variant, '') AS image_variant, COALESCE(spec_hash, '') AS spec_hash, COALESCE(kind, '') AS kind, telegram;
 		`,
 		bot.Name,
 		bot.Description,
 		bot.Icon,
-		bot.Id,
 		bot.ApiToken,
+		bot.ApiTokenKeyID,
+		bot.Id,
 	)
 	if err != nil {
 		return nil, err
@@ -202,6 +348,37 @@ func (repo *PostgresRepository) DeleteBotById(ctx context.Context, id int64) err
 	return nil
 }
 
+// RetireBotById hides a row from every query and frees its bot for a new
+// row, while keeping it around for RestoreBotById.
+func (repo *PostgresRepository) RetireBotById(ctx context.Context, id int64) error {
+	_, err := pu.Dispatch[dto.ContainerDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE bot_containers
+		SET deleted_at = now()
+		WHERE id = $1::bigint
+		  AND deleted_at IS NULL;
+		`,
+		id,
+	)
+	return err
+}
+
+func (repo *PostgresRepository) RestoreBotById(ctx context.Context, id int64) error {
+	_, err := pu.Dispatch[dto.ContainerDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE bot_containers
+		SET deleted_at = NULL
+		WHERE id = $1::bigint;
+		`,
+		id,
+	)
+	return err
+}
+
 func (repo *PostgresRepository) DeleteBotByContainerId(ctx context.Context, container_id string) error {
 	_, err := pu.Dispatch[dto.ContainerDbo](
 		ctx,
@@ -259,8 +436,11 @@ func (repo *PostgresRepository) SetBotState(ctx context.Context, state string, i
 }
 
 func (repo *PostgresRepository) StopBotState(ctx context.Context, id, bot_id int64) error {
-	tx := repo.db.MustBegin()
-	_, err := pu.DispatchTx[dto.ContainerDbo](
+	tx, err := repo.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, err = pu.DispatchTx[dto.ContainerDbo](
 		ctx,
 		tx,
 		`
@@ -290,6 +470,5 @@ func (repo *PostgresRepository) StopBotState(ctx context.Context, id, bot_id int
 		tx.Rollback()
 		return err
 	}
-	tx.Commit()
-	return nil
+	return tx.Commit()
 }
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/ports"
+	pu "executor/pkg/postgres_utils"
+)
+
+var ErrBotEnvNotFound = ports.ErrBotEnvNotFound
+
+// GetBotEnv returns the overrides of a bot; most bots have none.
+func (repo *PostgresRepository) GetBotEnv(ctx context.Context, bot_id int64) ([]dto.BotEnvDbo, error) {
+	return withRetry(ctx, repo, func() ([]dto.BotEnvDbo, error) {
+		return pu.Dispatch[dto.BotEnvDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT e.bot_id, e.key, e.value, e.secret, e.updated_at
+			FROM bot_container_env e
+			WHERE e.bot_id = $1::bigint
+			ORDER BY e.key;
+			`,
+			bot_id,
+		)
+	})
+}
+
+func (repo *PostgresRepository) SetBotEnv(ctx context.Context, env dto.BotEnvDbo) error {
+	_, err := pu.Dispatch[dto.BotEnvDbo](
+		ctx,
+		repo.db,
+		`
+		INSERT INTO bot_container_env (bot_id, key, value, secret, updated_at)
+		VALUES ($1::bigint, $2::text, $3::text, $4::boolean, now())
+		ON CONFLICT (bot_id, key) DO UPDATE
+		SET value = EXCLUDED.value,
+		    secret = EXCLUDED.secret,
+		    updated_at = now();
+		`,
+		env.BotID,
+		env.Key,
+		env.Value,
+		env.Secret,
+	)
+	return err
+}
+
+func (repo *PostgresRepository) DeleteBotEnv(ctx context.Context, bot_id int64, key string) error {
+	rows, err := pu.Dispatch[dto.BotEnvDbo](
+		ctx,
+		repo.db,
+		`
+		DELETE FROM bot_container_env
+		WHERE bot_id = $1::bigint
+		  AND key = $2::text
+		RETURNING bot_id, key, value, secret, updated_at;
+		`,
+		bot_id,
+		key,
+	)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return ErrBotEnvNotFound
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/ports"
+	pu "executor/pkg/postgres_utils"
+	"time"
+)
+
+var ErrExecutorsNotFound = ports.ErrExecutorsNotFound
+
+func (repo *PostgresRepository) RegisterExecutor(ctx context.Context, id, host string) error {
+	_, err := pu.Dispatch[dto.ExecutorDbo](
+		ctx,
+		repo.db,
+		`
+		INSERT INTO executors (id, host, draining, started_at, heartbeat_at)
+		VALUES ($1::text, $2::text, false, now(), now())
+		ON CONFLICT (id) DO UPDATE
+		SET host = EXCLUDED.host,
+		    draining = false,
+		    started_at = now(),
+		    heartbeat_at = now();
+		`,
+		id,
+		host,
+	)
+	return err
+}
+
+func (repo *PostgresRepository) HeartbeatExecutor(ctx context.Context, id string) error {
+	_, err := pu.Dispatch[dto.ExecutorDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE executors
+		SET heartbeat_at = now()
+		WHERE id = $1::text;
+		`,
+		id,
+	)
+	return err
+}
+
+func (repo *PostgresRepository) SetExecutorDraining(ctx context.Context, id string, draining bool) error {
+	_, err := pu.Dispatch[dto.ExecutorDbo](
+		ctx,
+		repo.db,
+		`
+		UPDATE executors
+		SET draining = $1::boolean
+		WHERE id = $2::text;
+		`,
+		draining,
+		id,
+	)
+	return err
+}
+
+func (repo *PostgresRepository) GetLiveExecutors(ctx context.Context, ttl time.Duration) ([]dto.ExecutorDbo, error) {
+	rows, err := withRetry(ctx, repo, func() ([]dto.ExecutorDbo, error) {
+		return pu.Dispatch[dto.ExecutorDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT e.id, e.host, e.draining, e.started_at, e.heartbeat_at, count(b.id) AS load
+			FROM executors e
+			LEFT JOIN bot_containers b
+			       ON b.executor_id = e.id
+			      AND b.deleted_at IS NULL
+			WHERE NOT e.draining
+			  AND e.heartbeat_at > now() - make_interval(secs => $1::double precision)
+			GROUP BY e.id;
+			`,
+			ttl.Seconds(),
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrExecutorsNotFound
+	}
+	return rows, nil
+}
+
+// IsExecutorAlive counts draining executors as alive: they still own their
+// bots until the handoff is done.
+func (repo *PostgresRepository) IsExecutorAlive(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	rows, err := withRetry(ctx, repo, func() ([]dto.ExecutorDbo, error) {
+		return pu.Dispatch[dto.ExecutorDbo](
+			ctx,
+			repo.db,
+			`
+			SELECT e.id, e.host, e.draining, e.started_at, e.heartbeat_at
+			FROM executors e
+			WHERE e.id = $1::text
+			  AND e.heartbeat_at > now() - make_interval(secs => $2::double precision);
+			`,
+			id,
+			ttl.Seconds(),
+		)
+	})
+	if err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
+func (repo *PostgresRepository) PurgeStaleExecutors(ctx context.Context, older_than time.Duration) error {
+	_, err := pu.Dispatch[dto.ExecutorDbo](
+		ctx,
+		repo.db,
+		`
+		DELETE FROM executors e
+		WHERE e.heartbeat_at < now() - make_interval(secs => $1::double precision)
+		  AND NOT EXISTS (
+		      SELECT 1
+		      FROM bot_containers b
+		      WHERE b.executor_id = e.id
+		        AND b.deleted_at IS NULL
+		  );
+		`,
+		older_than.Seconds(),
+	)
+	return err
+}
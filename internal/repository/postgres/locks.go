@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"executor/internal/core/ports"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrLockNotAcquired = errors.New("could not acquire bot lock")
+
+// LockBot takes a session-level advisory lock keyed by bot_id on a dedicated
+// connection. The lock is held until the returned unlock func is called or the
+// connection is lost, so every executor sharing the database serializes
+// lifecycle operations on the same bot.
+func (repo *PostgresRepository) LockBot(ctx context.Context, bot_id int64) (func() error, error) {
+	conn, err := repo.db.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: bot_id: %d: %w", ErrLockNotAcquired, bot_id, err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock(hashtextextended('executor:bot:' || $1::text, 0));`, bot_id); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("%w: bot_id: %d: %w", ErrLockNotAcquired, bot_id, err)
+	}
+	unlock := func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtextextended('executor:bot:' || $1::text, 0));`, bot_id)
+		return err
+	}
+	return unlock, nil
+}
+
+type advisoryLease struct {
+	conn *sqlx.Conn
+	name string
+}
+
+// TryLock takes a named session-level advisory lock without waiting. It
+// returns a nil lease when another session holds the lock. Losing the
+// connection releases the lock on the server, which Alive reports.
+func (repo *PostgresRepository) TryLock(ctx context.Context, name string) (ports.Lease, error) {
+	conn, err := repo.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var acquired bool
+	if err := conn.QueryRowxContext(ctx, `SELECT pg_try_advisory_lock(hashtextextended('executor:lease:' || $1::text, 0));`, name).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, nil
+	}
+	return &advisoryLease{conn: conn, name: name}, nil
+}
+
+func (l *advisoryLease) Alive(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+func (l *advisoryLease) Release() error {
+	defer l.conn.Close()
+	_, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock(hashtextextended('executor:lease:' || $1::text, 0));`, l.name)
+	return err
+}
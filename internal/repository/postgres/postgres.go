@@ -1,10 +1,13 @@
 package postgres
 
 import (
+	"context"
 	"errors"
 	"executor/internal/core/config"
+	"executor/pkg/backoff"
 	pu "executor/pkg/postgres_utils"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -21,6 +24,10 @@ type PostgresRepository struct {
 	migrationsPath string
 	version        uint
 	dirty          bool
+	pingAttempts   int
+	retryAttempts  int
+	retryMin       time.Duration
+	retryMax       time.Duration
 }
 
 func NewPostgresRepository(cfg *config.ExecutorConfig) *PostgresRepository {
@@ -42,37 +49,73 @@ func (repo *PostgresRepository) InvokeConnect(cfg *config.ExecutorConfig) error
 	)
 	repo.uri = postgres_uri
 	repo.migrationsPath = cfg.Postgres.MigrationsPath
+	repo.pingAttempts = cfg.Postgres.PingAttempts
+	repo.retryAttempts = cfg.Postgres.RetryAttempts
+	repo.retryMin = cfg.Postgres.RetryMin
+	repo.retryMax = cfg.Postgres.RetryMax
 
 	db, err := sqlx.Open("postgres", postgres_uri)
 	if err != nil {
-		return ErrConnect
+		return fmt.Errorf("%w: %w", ErrConnect, err)
 	}
+	db.SetMaxOpenConns(cfg.Postgres.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.Postgres.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.Postgres.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.Postgres.ConnMaxIdleTime)
 	repo.db = db
-	if err := repo.PingTest(); err != nil {
-		panic(err)
-	}
-	return nil
+	return repo.PingTest()
 }
 
 func (repo *PostgresRepository) PingTest() error {
-	max_errs := 5
-	errs := 0
-	timeout := 1 * time.Second
-	for max_errs > 0 {
+	b := backoff.New(repo.retryMin, repo.retryMax)
+	err := backoff.Retry(context.Background(), repo.pingAttempts, b, func() error {
 		if err := repo.db.Ping(); err != nil {
-			fmt.Printf("could not ping database: %s\n", err.Error())
-			fmt.Printf("retrying in %s\n", timeout)
-			max_errs--
-			errs++
-			time.Sleep(timeout)
+			fmt.Printf("could not ping database (attempt %d of %d): %s\n", b.Attempt()+1, repo.pingAttempts, err.Error())
+			return err
 		}
-		max_errs = 0
-		errs = 0
-	}
-	if errs == 0 {
 		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: postgres_host: %s: %w", ErrPing, redactURI(repo.uri), err)
 	}
-	return fmt.Errorf("%w: postgres_uri: %s", ErrPing, repo.uri)
+	return nil
+}
+
+func redactURI(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return u.Redacted()
+}
+
+func withRetry[T any](ctx context.Context, repo *PostgresRepository, fn func() (T, error)) (T, error) {
+	var res T
+	b := backoff.New(repo.retryMin, repo.retryMax)
+	attempts := repo.retryAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for i := 0; ; i++ {
+		r, err := fn()
+		if err == nil {
+			return r, nil
+		}
+		if i == attempts-1 || !pu.IsTransient(err) {
+			return res, err
+		}
+		fmt.Printf("transient database error, retrying (attempt %d of %d): %s\n", i+1, attempts, err.Error())
+		if err := backoff.Sleep(ctx, b.Next()); err != nil {
+			return res, err
+		}
+	}
+}
+
+func (repo *PostgresRepository) Check(ctx context.Context) error {
+	if err := repo.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrPing, err)
+	}
+	return nil
 }
 
 func (repo *PostgresRepository) Close() {
@@ -0,0 +1,131 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrStaleMessage = errors.New("message is older than the last applied action")
+
+const (
+	messageKeyPrefix     = "executor:messages:"
+	lastAppliedKeyPrefix = "executor:bots:last_applied:"
+
+	messageProcessing = "processing"
+	messageDone       = "done"
+)
+
+// only moves the stored timestamp forward so out-of-order completions cannot
+// rewind it; the key expires like the message ids, so deleted bots do not
+// leave entries behind
+var setLastAppliedScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+local ts = tonumber(ARGV[1])
+if ts > current then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+else
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+func lastAppliedKey(bot_id int64) string {
+	return lastAppliedKeyPrefix + strconv.FormatInt(bot_id, 10)
+}
+
+func (repo *RedisRepository) ClaimMessage(ctx context.Context, id string) (bool, error) {
+	return repo.rdb.SetNX(ctx, messageKeyPrefix+id, messageProcessing, repo.processingTTL).Result()
+}
+
+func (repo *RedisRepository) CompleteMessage(ctx context.Context, id string) error {
+	return repo.rdb.Set(ctx, messageKeyPrefix+id, messageDone, repo.dedupTTL).Err()
+}
+
+func (repo *RedisRepository) ReleaseMessage(ctx context.Context, id string) error {
+	return repo.rdb.Del(ctx, messageKeyPrefix+id).Err()
+}
+
+func (repo *RedisRepository) GetLastApplied(ctx context.Context, bot_id int64) (int64, error) {
+	val, err := repo.rdb.Get(ctx, lastAppliedKey(bot_id)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return val, err
+}
+
+func (repo *RedisRepository) SetLastApplied(ctx context.Context, bot_id, timestamp int64) error {
+	return setLastAppliedScript.Run(ctx, repo.rdb, []string{lastAppliedKey(bot_id)}, timestamp, repo.dedupTTL.Milliseconds()).Err()
+}
+
+// messageStore keeps the claims and last applied timestamps Idempotent
+// relies on; RedisRepository is the only implementation.
+type messageStore interface {
+	ClaimMessage(ctx context.Context, id string) (bool, error)
+	CompleteMessage(ctx context.Context, id string) error
+	ReleaseMessage(ctx context.Context, id string) error
+	GetLastApplied(ctx context.Context, bot_id int64) (int64, error)
+	SetLastApplied(ctx context.Context, bot_id, timestamp int64) error
+}
+
+// Idempotent skips messages whose ID was already processed or is being
+// processed and rejects messages older than the bot's last applied action.
+// Every executor receives every message, so claims are kept per executor;
+// only the one that applies a message records it as the bot's last action.
+func (c *RepositoryConsumer) Idempotent(handler customHandler) customHandler {
+	return func(ctx context.Context, message models.BotMessage) error {
+		bot_id := message.Payload.BotID
+		if message.Broadcast() && message.ID == "" {
+			return handler(ctx, message)
+		}
+		if !message.Broadcast() && message.Timestamp > 0 {
+			last, err := c.messages.GetLastApplied(ctx, bot_id)
+			if err != nil {
+				return err
+			}
+			if message.Timestamp < last {
+				return fmt.Errorf("%w: bot_id: %d, message: %s, timestamp: %d, last_applied: %d", ErrStaleMessage, bot_id, message.ID, message.Timestamp, last)
+			}
+		}
+		if message.ID == "" {
+			fmt.Printf("[bot_id: %d] message has no id, deduplication skipped\n", bot_id)
+			return c.apply(ctx, handler, message)
+		}
+		claim := fmt.Sprintf("%s:%s", message.ID, c.executorID)
+		claimed, err := c.messages.ClaimMessage(ctx, claim)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			fmt.Printf("[%s] duplicate message acknowledged, skipping\n", message.ID)
+			return nil
+		}
+		if err := c.apply(ctx, handler, message); err != nil {
+			// let a retry from the publisher run again
+			if rerr := c.messages.ReleaseMessage(context.WithoutCancel(ctx), claim); rerr != nil {
+				fmt.Printf("[%s] could not release message: %s\n", message.ID, rerr.Error())
+			}
+			return err
+		}
+		return c.messages.CompleteMessage(context.WithoutCancel(ctx), claim)
+	}
+}
+
+func (c *RepositoryConsumer) apply(ctx context.Context, handler customHandler, message models.BotMessage) error {
+	if err := handler(ctx, message); err != nil {
+		if errors.Is(err, ports.ErrNotOwner) {
+			fmt.Printf("[bot_id: %d] owned by another executor, skipping\n", message.Payload.BotID)
+			return nil
+		}
+		return err
+	}
+	if message.Timestamp > 0 && !message.Broadcast() {
+		return c.messages.SetLastApplied(context.WithoutCancel(ctx), message.Payload.BotID, message.Timestamp)
+	}
+	return nil
+}
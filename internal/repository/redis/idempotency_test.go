@@ -0,0 +1,106 @@
+package redis
+
+import (
+	"context"
+	"executor/internal/core/models"
+	"executor/internal/core/ports"
+	"fmt"
+	"testing"
+)
+
+// memoryStore is shared by the consumers of one test like Redis would be.
+type memoryStore struct {
+	claims      map[string]bool
+	lastApplied map[int64]int64
+}
+
+func (s *memoryStore) ClaimMessage(ctx context.Context, id string) (bool, error) {
+	if s.claims[id] {
+		return false, nil
+	}
+	s.claims[id] = true
+	return true, nil
+}
+
+func (s *memoryStore) CompleteMessage(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *memoryStore) ReleaseMessage(ctx context.Context, id string) error {
+	delete(s.claims, id)
+	return nil
+}
+
+func (s *memoryStore) GetLastApplied(ctx context.Context, bot_id int64) (int64, error) {
+	return s.lastApplied[bot_id], nil
+}
+
+func (s *memoryStore) SetLastApplied(ctx context.Context, bot_id, timestamp int64) error {
+	s.lastApplied[bot_id] = max(s.lastApplied[bot_id], timestamp)
+	return nil
+}
+
+func TestIdempotentRunsOnOwner(t *testing.T) {
+	store := &memoryStore{claims: map[string]bool{}, lastApplied: map[int64]int64{}}
+	var applied []string
+	consumer := func(id string) customHandler {
+		c := &RepositoryConsumer{messages: store, executorID: id}
+		return c.Idempotent(func(ctx context.Context, m models.BotMessage) error {
+			if id != "owner" {
+				return fmt.Errorf("%w: bot_id: %d", ports.ErrNotOwner, m.Payload.BotID)
+			}
+			applied = append(applied, m.ID)
+			return nil
+		})
+	}
+	other, owner := consumer("other"), consumer("owner")
+	run := models.BotMessage{ID: "run", Type: "run", Timestamp: 1, Payload: models.BotPayload{BotID: 7}}
+	stop := models.BotMessage{ID: "stop", Type: "stop", Timestamp: 2, Payload: models.BotPayload{BotID: 7}}
+
+	// the other executor sees both messages before the owner sees either
+	for _, m := range []models.BotMessage{run, stop} {
+		if err := other(context.Background(), m); err != nil {
+			t.Fatalf("other executor error = %v", err)
+		}
+	}
+	for _, m := range []models.BotMessage{run, run, stop} {
+		if err := owner(context.Background(), m); err != nil {
+			t.Fatalf("owner error = %v", err)
+		}
+	}
+	if fmt.Sprint(applied) != "[run stop]" {
+		t.Errorf("owner applied %v, want [run stop]", applied)
+	}
+	if store.lastApplied[7] != 2 {
+		t.Errorf("last applied = %d, want 2", store.lastApplied[7])
+	}
+}
+
+func TestWaitBroadcasts(t *testing.T) {
+	c := &RepositoryConsumer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	var handlerErr error
+	c.dispatchBroadcast(ctx, "bot", func(ctx context.Context, m models.BotMessage) error {
+		<-release
+		handlerErr = ctx.Err()
+		return nil
+	}, models.BotMessage{Type: string(models.UPGRADE)})
+	// the consumer stops before the rollout is done
+	cancel()
+	waited := make(chan struct{})
+	go func() {
+		c.WaitBroadcasts()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("WaitBroadcasts() returned while a rollout was running")
+	default:
+	}
+	close(release)
+	<-waited
+	if handlerErr != nil {
+		t.Errorf("rollout context error = %v, want it to outlive the consumer", handlerErr)
+	}
+}
@@ -4,37 +4,84 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"executor/internal/core/config"
 	"executor/internal/core/models"
+	"executor/pkg/backoff"
+	"executor/pkg/workerpool"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-var ErrPing = errors.New("could not ping database")
+var (
+	ErrPing               = errors.New("could not ping database")
+	ErrNotListening       = errors.New("consumer is not listening")
+	ErrNotConnected       = errors.New("consumer is not connected")
+	ErrSubscriptionClosed = errors.New("subscription channel closed")
+)
+
+type ConnState int32
+
+const (
+	StateDisconnected ConnState = iota
+	StateConnecting
+	StateConnected
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	default:
+		return "disconnected"
+	}
+}
 
 type RedisRepository struct {
-	rdb    *redis.Client
-	config *redis.Options
+	rdb           *redis.Client
+	config        *redis.Options
+	pingAttempts  int
+	reconnectMin  time.Duration
+	reconnectMax  time.Duration
+	dedupTTL      time.Duration
+	processingTTL time.Duration
 }
 
 type RepositoryConsumer struct {
-	client       *RedisRepository
-	subscription *redis.PubSub
+	client     *RedisRepository
+	messages   messageStore
+	pool       *workerpool.Pool
+	executorID string
+	// rollouts runs broadcast messages one at a time; inflight tracks them
+	// for drain
+	rollouts  sync.Mutex
+	inflight  sync.WaitGroup
+	listening atomic.Int32
+	state     atomic.Int32
 }
 
-type customHandler func(models.BotMessage) error
+type customHandler func(context.Context, models.BotMessage) error
 
-func NewRepositoryConsumer(host, password string, port, db int) *RepositoryConsumer {
-	client := NewRedisRepository(host, password, port, db)
-	return &RepositoryConsumer{client: client}
+func NewRepositoryConsumer(cfg *config.ExecutorConfig, pool *workerpool.Pool) *RepositoryConsumer {
+	client := NewRedisRepository(cfg)
+	return &RepositoryConsumer{client: client, messages: client, pool: pool, executorID: cfg.Executor.ID}
 }
 
-func NewRedisRepository(host, password string, port, db int) *RedisRepository {
-	repo := &RedisRepository{}
-	if err := repo.InvokeConnect(host, password, port, db); err != nil {
-		e := fmt.Errorf("REDIS: redis://default:%s@%s:%d/%d\n%w", password, host, port, db, err)
-		panic(e)
+func NewRedisRepository(cfg *config.ExecutorConfig) *RedisRepository {
+	repo := &RedisRepository{
+		pingAttempts:  cfg.Redis.PingAttempts,
+		reconnectMin:  cfg.Redis.ReconnectMin,
+		reconnectMax:  cfg.Redis.ReconnectMax,
+		dedupTTL:      cfg.Redis.DedupTTL,
+		processingTTL: cfg.Docker.MessageTimeout,
+	}
+	if err := repo.InvokeConnect(cfg.Redis.Host, cfg.Redis.RedisPassword, cfg.Redis.Port, cfg.Redis.DB); err != nil {
+		fmt.Printf("REDIS: %s:%d/%d unavailable, starting degraded: %s\n", cfg.Redis.Host, cfg.Redis.Port, cfg.Redis.DB, err.Error())
 	}
 	return repo
 }
@@ -49,31 +96,64 @@ func (repo *RedisRepository) InvokeConnect(host, password string, port, db int)
 	rdb := redis.NewClient(&conf)
 	repo.config = &conf
 	repo.rdb = rdb
-	if err := repo.PingTest(); err != nil {
-		panic(err)
-	}
-	return nil
+	return repo.PingTest()
 }
 
 func (repo *RedisRepository) PingTest() error {
-	max_errs := 5
-	errs := 0
-	timeout := 1 * time.Second
-	for max_errs > 0 {
+	b := backoff.New(repo.reconnectMin, repo.reconnectMax)
+	err := backoff.Retry(context.Background(), repo.pingAttempts, b, func() error {
 		if err := repo.rdb.Ping(context.Background()).Err(); err != nil {
-			fmt.Printf("could not ping database: %s\n", err.Error())
-			fmt.Printf("retrying in %s\n", timeout)
-			max_errs--
-			errs++
-			time.Sleep(timeout)
+			fmt.Printf("could not ping database (attempt %d of %d): %s\n", b.Attempt()+1, repo.pingAttempts, err.Error())
+			return err
 		}
-		max_errs = 0
-		errs = 0
-	}
-	if errs == 0 {
 		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: redis_uri: %s: %w", ErrPing, repo.config.Addr, err)
+	}
+	return nil
+}
+
+func (repo *RedisRepository) Check(ctx context.Context) error {
+	if err := repo.rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("%w: %w", ErrPing, err)
+	}
+	return nil
+}
+
+func (c *RepositoryConsumer) State() ConnState {
+	return ConnState(c.state.Load())
+}
+
+func (c *RepositoryConsumer) setState(queue string, state ConnState) {
+	if prev := ConnState(c.state.Swap(int32(state))); prev != state {
+		fmt.Printf("[%s] consumer state: %s -> %s\n", queue, prev, state)
 	}
-	return fmt.Errorf("%w: redis_uri: %s", ErrPing, repo.config.Addr)
+}
+
+func (c *RepositoryConsumer) Alive(ctx context.Context) error {
+	if c.listening.Load() == 0 {
+		return ErrNotListening
+	}
+	return nil
+}
+
+func (c *RepositoryConsumer) Check(ctx context.Context) error {
+	if err := c.Alive(ctx); err != nil {
+		return err
+	}
+	if state := c.State(); state != StateConnected {
+		return fmt.Errorf("%w: %s", ErrNotConnected, state)
+	}
+	return c.client.Check(ctx)
+}
+
+func (c *RepositoryConsumer) Publish(ctx context.Context, queue string, message models.BotMessage) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return c.client.rdb.Publish(ctx, queue, payload).Err()
 }
 
 func (c *RepositoryConsumer) ConsumerMessages(ctx context.Context, queue_names []string, handler customHandler) {
@@ -91,29 +171,94 @@ func (c *RepositoryConsumer) handleMessage(ctx context.Context, queue string, ha
 	consumerCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	c.listening.Add(1)
+	defer c.listening.Add(-1)
+
+	b := backoff.New(c.client.reconnectMin, c.client.reconnectMax)
+	for {
+		err := c.consume(consumerCtx, queue, handler, b)
+		if consumerCtx.Err() != nil {
+			c.setState(queue, StateDisconnected)
+			fmt.Printf("[%s] consumer stopped listening...\n", queue)
+			return
+		}
+		c.setState(queue, StateDisconnected)
+		delay := b.Next()
+		fmt.Printf("[%s] subscription lost: %s\n", queue, err.Error())
+		fmt.Printf("[%s] resubscribing in %s (attempt %d)\n", queue, delay, b.Attempt())
+		if err := backoff.Sleep(consumerCtx, delay); err != nil {
+			fmt.Printf("[%s] consumer stopped listening...\n", queue)
+			return
+		}
+	}
+}
+
+func (c *RepositoryConsumer) consume(ctx context.Context, queue string, handler customHandler, b *backoff.Backoff) error {
+	c.setState(queue, StateConnecting)
 	fmt.Printf("subscribing to queue: %s\n", queue)
-	c.subscription = c.client.rdb.Subscribe(consumerCtx, queue)
-	defer c.subscription.Close()
+	subscription := c.client.rdb.Subscribe(ctx, queue)
+	defer subscription.Close()
+
+	if _, err := subscription.Receive(ctx); err != nil {
+		return err
+	}
+	c.setState(queue, StateConnected)
+	b.Reset()
 
-	channel := c.subscription.Channel()
+	channel := subscription.Channel()
 
 	fmt.Printf("[%s] consumer started listening...\n", queue)
 	for {
 		select {
-		case <-consumerCtx.Done():
-			fmt.Printf("[%s] consumer stopped listening...\n", queue)
-			return
-		case msg := <-channel:
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-channel:
+			if !ok {
+				return ErrSubscriptionClosed
+			}
 			var message models.BotMessage
 			err := json.Unmarshal([]byte(msg.Payload), &message)
 			if err != nil {
 				fmt.Printf("[%s] could not unmarshal message: %s\n", queue, err.Error())
 				continue
 			}
-			if err := handler(message); err != nil {
-				fmt.Printf("[%s] %s\n", queue, err.Error())
+			if message.Broadcast() {
+				c.dispatchBroadcast(ctx, queue, handler, message)
 				continue
 			}
+			err = c.pool.Submit(ctx, message.Payload.BotID, func(ctx context.Context) {
+				if err := handler(ctx, message); err != nil {
+					fmt.Printf("[%s] %s\n", queue, err.Error())
+				}
+			})
+			if err != nil {
+				fmt.Printf("[%s] could not dispatch message: %s\n", queue, err.Error())
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+			}
 		}
 	}
 }
+
+// dispatchBroadcast runs a rollout outside the worker pool: it carries no
+// bot_id and can take up to upgrade.timeout, which would hold up every bot
+// hashed to the same shard. Rollouts still run one after another and, like
+// pool messages, outlive the consumer until WaitBroadcasts returns.
+func (c *RepositoryConsumer) dispatchBroadcast(ctx context.Context, queue string, handler customHandler, message models.BotMessage) {
+	ctx = context.WithoutCancel(ctx)
+	c.inflight.Add(1)
+	go func() {
+		defer c.inflight.Done()
+		c.rollouts.Lock()
+		defer c.rollouts.Unlock()
+		if err := handler(ctx, message); err != nil {
+			fmt.Printf("[%s] %s\n", queue, err.Error())
+		}
+	}()
+}
+
+// WaitBroadcasts blocks until every dispatched rollout has returned.
+func (c *RepositoryConsumer) WaitBroadcasts() {
+	c.inflight.Wait()
+}
@@ -0,0 +1,113 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/ports"
+	"executor/internal/placement"
+	"fmt"
+	"os"
+	"time"
+)
+
+type ShardingService struct {
+	id       string
+	host     string
+	repo     ports.ExecutorsRepository
+	strategy placement.Strategy
+	interval time.Duration
+	ttl      time.Duration
+}
+
+func NewShardingService(repo ports.ExecutorsRepository, cfg *config.ExecutorConfig) *ShardingService {
+	strategy, err := placement.NewStrategy(cfg.Executor.Placement)
+	if err != nil {
+		panic(err)
+	}
+	host, _ := os.Hostname()
+	return &ShardingService{
+		id:       cfg.Executor.ID,
+		host:     host,
+		repo:     repo,
+		strategy: strategy,
+		interval: cfg.Executor.HeartbeatInterval,
+		ttl:      cfg.Executor.HeartbeatTTL,
+	}
+}
+
+func (s *ShardingService) ExecutorID() string {
+	return s.id
+}
+
+func (s *ShardingService) ExecutorAlive(ctx context.Context, id string) (bool, error) {
+	return s.repo.IsExecutorAlive(ctx, id, s.ttl)
+}
+
+func (s *ShardingService) Register(ctx context.Context) error {
+	if err := s.repo.RegisterExecutor(ctx, s.id, s.host); err != nil {
+		return err
+	}
+	fmt.Printf("[%s] executor registered (host: %s)\n", s.id, s.host)
+	return nil
+}
+
+func (s *ShardingService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.repo.HeartbeatExecutor(ctx, s.id); err != nil {
+				fmt.Printf("[%s] heartbeat failed: %s\n", s.id, err.Error())
+			}
+		}
+	}
+}
+
+func (s *ShardingService) SetDraining(ctx context.Context, draining bool) error {
+	return s.repo.SetExecutorDraining(ctx, s.id, draining)
+}
+
+// Owns reports whether this executor should handle the bot. Existing rows are
+// owned by the executor recorded on them while it keeps heartbeating; bots
+// that are unassigned or whose executor died go through the placement
+// strategy over live, non-draining executors.
+func (s *ShardingService) Owns(ctx context.Context, bot_id int64, existing *dto.ContainerDbo) (bool, error) {
+	if existing != nil && existing.ExecutorID != "" && existing.ExecutorID != s.id {
+		alive, err := s.ExecutorAlive(ctx, existing.ExecutorID)
+		if err != nil || alive {
+			return false, err
+		}
+	}
+	if existing != nil && existing.ExecutorID == s.id {
+		return true, nil
+	}
+	target, err := s.Place(ctx, bot_id)
+	if err != nil {
+		return false, err
+	}
+	return target == s.id, nil
+}
+
+func (s *ShardingService) Place(ctx context.Context, bot_id int64) (string, error) {
+	executors, err := s.repo.GetLiveExecutors(ctx, s.ttl)
+	if err != nil {
+		return "", err
+	}
+	candidates := make([]placement.Candidate, 0, len(executors))
+	for _, e := range executors {
+		candidates = append(candidates, placement.Candidate{ID: e.ID, Load: e.Load})
+	}
+	target, err := s.strategy.Place(bot_id, candidates)
+	if err != nil {
+		if errors.Is(err, placement.ErrNoCandidates) {
+			return "", fmt.Errorf("%w: bot_id: %d", err, bot_id)
+		}
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,47 @@
+package sharding
+
+import (
+	"context"
+	"executor/internal/application/dto"
+	"executor/internal/core/config"
+	"executor/internal/core/ports"
+	"testing"
+	"time"
+)
+
+type fakeExecutors struct {
+	ports.ExecutorsRepository
+	live map[string]bool
+}
+
+func (r *fakeExecutors) GetLiveExecutors(ctx context.Context, ttl time.Duration) ([]dto.ExecutorDbo, error) {
+	var rows []dto.ExecutorDbo
+	for id, alive := range r.live {
+		if alive {
+			rows = append(rows, dto.ExecutorDbo{ID: id})
+		}
+	}
+	return rows, nil
+}
+
+func (r *fakeExecutors) IsExecutorAlive(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return r.live[id], nil
+}
+
+func TestOwnsOrphanedBot(t *testing.T) {
+	cfg := &config.ExecutorConfig{}
+	cfg.Executor.ID = "a"
+	cfg.Executor.Placement = "hash"
+	repo := &fakeExecutors{live: map[string]bool{"a": true, "b": true}}
+	s := NewShardingService(repo, cfg)
+	ctx := context.Background()
+
+	if owned, _ := s.Owns(ctx, 1, &dto.ContainerDbo{BotID: 1, ExecutorID: "b"}); owned {
+		t.Errorf("Owns() = true for a bot of a live executor, want false")
+	}
+	// with b gone, a is the only candidate left
+	repo.live["b"] = false
+	if owned, err := s.Owns(ctx, 1, &dto.ContainerDbo{BotID: 1, ExecutorID: "b"}); err != nil || !owned {
+		t.Errorf("Owns() = %v, %v for a bot of a dead executor, want true", owned, err)
+	}
+}
@@ -0,0 +1,163 @@
+package tokencrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"executor/internal/core/config"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrUnknownKey = errors.New("unknown token encryption key")
+	ErrMalformed  = errors.New("malformed encrypted token")
+	ErrInvalidKey = errors.New("invalid token encryption key")
+)
+
+const keySize = 32
+
+// Keyring does envelope encryption: every token is sealed with its own data
+// key, which is sealed with a configured key. The key ID stored with each
+// row says which key opens it.
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+}
+
+func New(cfg config.TokenEncryption) (*Keyring, error) {
+	k := &Keyring{active: cfg.KeyID, keys: make(map[string][]byte, len(cfg.Keys))}
+	for _, key := range cfg.Keys {
+		encoded := key.Key
+		if key.KeyFile != "" {
+			data, err := os.ReadFile(key.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidKey, key.ID, err)
+			}
+			encoded = strings.TrimSpace(string(data))
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != keySize {
+			return nil, fmt.Errorf("%w: %s: want %d base64 encoded bytes", ErrInvalidKey, key.ID, keySize)
+		}
+		k.keys[key.ID] = raw
+	}
+	return k, nil
+}
+
+// ActiveKeyID is the key new tokens are encrypted with; empty when
+// encryption is off.
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Encrypt seals token with the active key and returns the key ID to store
+// with it. Without an active key the token is returned unchanged.
+func (k *Keyring) Encrypt(token string) (string, string, error) {
+	if k.active == "" {
+		return token, "", nil
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey, []byte(k.active))
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := seal(dataKey, []byte(token), nil)
+	if err != nil {
+		return "", "", err
+	}
+	enc := base64.RawStdEncoding
+	return enc.EncodeToString(wrapped) + "." + enc.EncodeToString(sealed), k.active, nil
+}
+
+// Knows reports whether tokens stored with keyID can be opened; plaintext
+// tokens, with an empty keyID, always can.
+func (k *Keyring) Knows(keyID string) bool {
+	_, ok := k.keys[keyID]
+	return ok || keyID == ""
+}
+
+// Decrypt opens a token stored with keyID; an empty keyID means the token
+// was stored in plaintext.
+func (k *Keyring) Decrypt(token, keyID string) (string, error) {
+	if keyID == "" {
+		return token, nil
+	}
+	key, ok := k.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	wrappedPart, sealedPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", ErrMalformed
+	}
+	enc := base64.RawStdEncoding
+	wrapped, err := enc.DecodeString(wrappedPart)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	sealed, err := enc.DecodeString(sealedPart)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	dataKey, err := open(key, wrapped, []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(dataKey, sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Reencrypt moves a token onto the active key.
+func (k *Keyring) Reencrypt(token, keyID string) (string, string, error) {
+	plain, err := k.Decrypt(token, keyID)
+	if err != nil {
+		return "", "", err
+	}
+	return k.Encrypt(plain)
+}
+
+func seal(key, plain, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, body := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, body, aad)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformed, err)
+	}
+	return plain, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,53 @@
+package tokencrypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"executor/internal/core/config"
+	"strings"
+	"testing"
+)
+
+func key(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), keySize)))
+}
+
+func TestRotation(t *testing.T) {
+	old, err := New(config.TokenEncryption{KeyID: "k1", Keys: []config.EncryptionKey{{ID: "k1", Key: key('a')}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, keyID, err := old.Encrypt("123:abc")
+	if err != nil || keyID != "k1" || strings.Contains(sealed, "123:abc") {
+		t.Fatalf("Encrypt() = %v, %v, %v", sealed, keyID, err)
+	}
+
+	k, _ := New(config.TokenEncryption{KeyID: "k2", Keys: []config.EncryptionKey{{ID: "k1", Key: key('a')}, {ID: "k2", Key: key('b')}}})
+	resealed, keyID, err := k.Reencrypt(sealed, "k1")
+	if err != nil || keyID != "k2" {
+		t.Fatalf("Reencrypt() = %v, %v, %v", resealed, keyID, err)
+	}
+	if got, err := k.Decrypt(resealed, keyID); err != nil || got != "123:abc" {
+		t.Errorf("Decrypt() = %v, %v, want 123:abc", got, err)
+	}
+	if _, err := old.Decrypt(resealed, keyID); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Decrypt() with retired keyring error = %v, want %v", err, ErrUnknownKey)
+	}
+	if got, _ := k.Decrypt("plain", ""); got != "plain" {
+		t.Errorf("Decrypt() of plaintext row = %v, want plain", got)
+	}
+	if !k.Knows("k1") || old.Knows("k2") || !old.Knows("") {
+		t.Errorf("Knows() does not match the configured keys")
+	}
+}
+
+func TestTampered(t *testing.T) {
+	k, _ := New(config.TokenEncryption{KeyID: "k1", Keys: []config.EncryptionKey{{ID: "k1", Key: key('a')}}})
+	sealed, keyID, _ := k.Encrypt("123:abc")
+	if _, err := k.Decrypt(sealed[:len(sealed)-2], keyID); !errors.Is(err, ErrMalformed) {
+		t.Errorf("Decrypt() of tampered token error = %v, want %v", err, ErrMalformed)
+	}
+	if _, err := New(config.TokenEncryption{Keys: []config.EncryptionKey{{ID: "short", Key: "YWJj"}}}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("New() with short key error = %v, want %v", err, ErrInvalidKey)
+	}
+}
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS bot_containers_active_bot_uidx;
@@ -0,0 +1,14 @@
+-- keep only the newest active row of each bot before enforcing uniqueness
+UPDATE bot_containers
+SET deleted_at = now()
+WHERE deleted_at IS NULL
+  AND id NOT IN (
+    SELECT DISTINCT ON (bot_id, project_id, user_id) id
+    FROM bot_containers
+    WHERE deleted_at IS NULL
+    ORDER BY bot_id, project_id, user_id, created_at DESC NULLS LAST, id DESC
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS bot_containers_active_bot_uidx
+    ON bot_containers (bot_id, project_id, user_id)
+    WHERE deleted_at IS NULL;
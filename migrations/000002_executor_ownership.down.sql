@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS executors;
+DROP INDEX IF EXISTS bot_containers_executor_idx;
+ALTER TABLE bot_containers DROP COLUMN IF EXISTS executor_id;
@@ -0,0 +1,13 @@
+ALTER TABLE bot_containers ADD COLUMN IF NOT EXISTS executor_id text;
+
+CREATE INDEX IF NOT EXISTS bot_containers_executor_idx
+    ON bot_containers (executor_id)
+    WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS executors (
+    id           text PRIMARY KEY,
+    host         text NOT NULL,
+    draining     boolean NOT NULL DEFAULT false,
+    started_at   timestamptz NOT NULL DEFAULT now(),
+    heartbeat_at timestamptz NOT NULL DEFAULT now()
+);
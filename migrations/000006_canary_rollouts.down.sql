@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS canary_rollouts;
+
+ALTER TABLE bot_containers DROP COLUMN IF EXISTS image_variant;
@@ -0,0 +1,7 @@
+ALTER TABLE bot_containers ADD COLUMN IF NOT EXISTS image_variant text;
+
+CREATE TABLE IF NOT EXISTS canary_rollouts (
+    image      text PRIMARY KEY,
+    status     text NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1 @@
+ALTER TABLE bot_containers ADD COLUMN IF NOT EXISTS spec_hash text;
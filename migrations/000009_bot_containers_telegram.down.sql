@@ -0,0 +1 @@
+ALTER TABLE bot_containers DROP COLUMN IF EXISTS telegram;
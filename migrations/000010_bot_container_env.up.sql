@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS bot_container_env (
+    bot_id     bigint      NOT NULL,
+    key        text        NOT NULL,
+    value      text        NOT NULL,
+    secret     boolean     NOT NULL DEFAULT false,
+    updated_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (bot_id, key)
+);
@@ -0,0 +1,78 @@
+package backoff
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+type Backoff struct {
+	Min     time.Duration
+	Max     time.Duration
+	Factor  float64
+	Jitter  bool
+	attempt int
+}
+
+func New(min, max time.Duration) *Backoff {
+	return &Backoff{Min: min, Max: max, Factor: 2, Jitter: true}
+}
+
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+func (b *Backoff) Next() time.Duration {
+	d := float64(b.Min)
+	for i := 0; i < b.attempt; i++ {
+		d *= b.Factor
+		if d >= float64(b.Max) {
+			d = float64(b.Max)
+			break
+		}
+	}
+	b.attempt++
+	if d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+	if b.Jitter && d > 0 {
+		// equal jitter: keep half of the delay and randomize the rest
+		half := d / 2
+		d = half + rand.Float64()*half
+	}
+	return time.Duration(d)
+}
+
+func Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func Retry(ctx context.Context, attempts int, b *Backoff, fn func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if i == attempts-1 {
+			break
+		}
+		if err := Sleep(ctx, b.Next()); err != nil {
+			return err
+		}
+	}
+	return err
+}
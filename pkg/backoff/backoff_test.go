@@ -0,0 +1,42 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextBounds(t *testing.T) {
+	b := New(100*time.Millisecond, time.Second)
+	for i := 0; i < 10; i++ {
+		d := b.Next()
+		if d <= 0 || d > time.Second {
+			t.Errorf("Next() attempt %d = %v, want (0, %v]", i, d, time.Second)
+		}
+	}
+	b.Reset()
+	if b.Attempt() != 0 {
+		t.Errorf("Attempt() after Reset() = %v, want 0", b.Attempt())
+	}
+	if d := b.Next(); d > 100*time.Millisecond {
+		t.Errorf("Next() after Reset() = %v, want <= %v", d, 100*time.Millisecond)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), 3, New(time.Millisecond, time.Millisecond), func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Retry() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Retry() calls = %v, want 3", calls)
+	}
+}
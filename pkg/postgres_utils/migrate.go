@@ -65,6 +65,10 @@ func errLog(uri, path string, action Action, count int, err string) {
 	)
 }
 
+// MigrationsTable keeps the executor's schema version apart from other
+// services migrating the same database.
+const MigrationsTable = "executor_schema_migrations"
+
 func Migrate(uri string, path string, action Action) DBStatus {
 	if path == "" {
 		return DBStatus{Error: ErrMigrationPathNotExists}
@@ -72,7 +76,7 @@ func Migrate(uri string, path string, action Action) DBStatus {
 
 	instance, err := migrate.New(
 		fmt.Sprintf("file://%s", path),
-		uri,
+		fmt.Sprintf("%s&x-migrations-table=%s", uri, MigrationsTable),
 	)
 	if err != nil {
 		return DBStatus{Error: err}
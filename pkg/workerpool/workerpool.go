@@ -0,0 +1,85 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+type Task func(ctx context.Context)
+
+type Pool struct {
+	shards []chan Task
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+func New(size, queueSize int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	p := &Pool{shards: make([]chan Task, size)}
+	for i := range p.shards {
+		p.shards[i] = make(chan Task, queueSize)
+	}
+	return p
+}
+
+func (p *Pool) Size() int {
+	return len(p.shards)
+}
+
+func (p *Pool) Start(ctx context.Context) {
+	for _, shard := range p.shards {
+		p.wg.Add(1)
+		go func(shard chan Task) {
+			defer p.wg.Done()
+			for task := range shard {
+				task(ctx)
+			}
+		}(shard)
+	}
+}
+
+// Submit enqueues task on the shard owning key. Tasks sharing a key run in
+// submission order; Submit blocks while that shard's queue is full.
+func (p *Pool) Submit(ctx context.Context, key int64, task Task) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	select {
+	case p.shards[p.shard(key)] <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) shard(key int64) int {
+	k := uint64(key)
+	return int(k % uint64(len(p.shards)))
+}
+
+// Close stops accepting tasks and waits for queued ones to finish.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		p.wg.Wait()
+		return
+	}
+	p.closed = true
+	for _, shard := range p.shards {
+		close(shard)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
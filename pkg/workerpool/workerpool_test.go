@@ -0,0 +1,39 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestSubmitKeepsOrderPerKey(t *testing.T) {
+	p := New(4, 8)
+	p.Start(context.Background())
+
+	var mu sync.Mutex
+	got := map[int64][]int{}
+	for i := 0; i < 100; i++ {
+		key := int64(i % 3)
+		n := i
+		if err := p.Submit(context.Background(), key, func(ctx context.Context) {
+			mu.Lock()
+			got[key] = append(got[key], n)
+			mu.Unlock()
+		}); err != nil {
+			t.Fatalf("Submit() error = %v", err)
+		}
+	}
+	p.Close()
+
+	for key, seq := range got {
+		for i := 1; i < len(seq); i++ {
+			if seq[i] < seq[i-1] {
+				t.Errorf("key %d executed out of order: %v", key, seq)
+				break
+			}
+		}
+	}
+	if err := p.Submit(context.Background(), 1, func(ctx context.Context) {}); err != ErrPoolClosed {
+		t.Errorf("Submit() after Close() error = %v, want %v", err, ErrPoolClosed)
+	}
+}